@@ -39,6 +39,14 @@ type Column struct {
 	// Column scale.
 	Scale int64
 
+	// --- The following fields are the origin of the column, they are "" if unknown (e.g. expressions) ---
+
+	// OrgTable is the original table name (not alias) of the column.
+	OrgTable string
+
+	// OrgName is the original column name (not alias) in OrgTable.
+	OrgName string
+
 	// --- Use DataType instead of ScanType and DatabaseTypeName ---
 
 	// DataType is a 'translated' driver-specific type identifier (ignore nullable), such as:
@@ -59,6 +67,9 @@ type TableColumn struct {
 
 	// DefaultValue is the default value of the table column.
 	DefaultValue sql.NullString
+
+	// Comment is the comment of the table column, "" if none.
+	Comment string
 }
 
 // NewColumn extract information from sql.Column and returns Column.
@@ -8,7 +8,8 @@ import (
 //
 // NOTE: Use sql.Conn instead of sql.DB to make sure only one database connection is using.
 type Driver interface {
-	// LoadQueryResultColumns returns result columns of a query.
+	// LoadQueryResultColumns returns result columns of a query. It must not change any data. Callers should make
+	// the query return no rows (e.g. with "LIMIT 0") so that no table is scanned.
 	LoadQueryResultColumns(conn *sql.Conn, query string, args ...interface{}) (columns []*Column, err error)
 
 	// LoadTableNames returns all table names in current database.
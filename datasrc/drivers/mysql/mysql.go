@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/huangjunwen/sqlw/datasrc"
@@ -61,13 +62,19 @@ var (
 	scanTypeUnknown   = reflect.TypeOf(new(interface{}))
 )
 
-const (
-	// Copy from github.com/go-sql-driver/mysql/const.go
-	flagUnsigned = 1 << 5
-)
-
 func (driver mysqlDriver) LoadQueryResultColumns(conn *sql.Conn, query string, args ...interface{}) (columns []*datasrc.Column, err error) {
-	rows, err := conn.QueryContext(context.Background(), query, args...)
+
+	ctx := context.Background()
+
+	// Result metadata is read from a read-only transaction which is always rolled back, so the query never changes
+	// data. Callers should make the query return no rows (e.g. "LIMIT 0") to avoid scanning tables.
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -78,134 +85,143 @@ func (driver mysqlDriver) LoadQueryResultColumns(conn *sql.Conn, query string, a
 		return nil, err
 	}
 
-	for i, columnType := range columnTypes {
-
-		// XXX: From current driver's public API some information is lost:
-		// - Column type's length is not support yet (see https://github.com/go-sql-driver/mysql/pull/667)
-		// - Unsigned or not can't be determine when scan type is NullInt64
-		// Do some tricks to read them from private fields.
-		//
-		// XXX: In general reading data from private field is not a good idea, but i think here
-		// is ok since we're only using them to generate code
-		field := reflect.
-			ValueOf(rows).          // *sql.Rows
-			Elem().                 // sql.Rows
-			FieldByName("rowsi").   // driver.Rows
-			Elem().                 // *mysql.mysqlRows
-			Elem().                 // mysql.mysqlRows
-			FieldByName("rs").      // mysql.resultSet
-			FieldByName("columns"). // []mysql.mysqlField
-			Index(i)                // mysql.mysqlField
-
-		length := field.FieldByName("length").Uint()
-		flags := field.FieldByName("flags").Uint()
-		isUnsigned := (flags & flagUnsigned) != 0
-
-		databaseTypeName := columnType.DatabaseTypeName()
-		scanType := columnType.ScanType()
-
-		dataType := ""
-
-		bad := func() {
-			panic(fmt.Errorf("Unknown column type: scantype=%#v databaseTypeName=%+q", scanType, databaseTypeName))
+	for _, columnType := range columnTypes {
+		dataType, err := resultDataType(columnType)
+		if err != nil {
+			return nil, err
 		}
+		// NOTE: org_table/org_name in column definition packets are discarded by current driver,
+		// so OrgTable/OrgName of columns are left empty here.
+		columns = append(columns, datasrc.NewColumn(columnType, dataType))
+	}
 
-		switch scanType {
-		// Float types
-		case scanTypeFloat32:
-			dataType = "float32"
-		case scanTypeFloat64:
-			dataType = "float64"
-		case scanTypeNullFloat:
-			switch databaseTypeName {
-			case "FLOAT":
-				dataType = "float32"
-			case "DOUBLE":
-				dataType = "float64"
-			default:
-				bad()
-			}
+	return columns, nil
+
+}
+
+// resultDataType determines data type from public column type information.
+//
+// NOTE: Column length and unsigned flag of nullable integers are not exposed by the driver, so TINYINT(1) can't be
+// distinguished from TINYINT, and nullable integers of unknown signedness use the wider signed types. Result columns
+// referencing table columns get their precise data types from the table columns (see LoadTableColumns).
+func resultDataType(columnType *sql.ColumnType) (string, error) {
+
+	scanType := columnType.ScanType()
+	databaseTypeName := columnType.DatabaseTypeName()
+
+	// Newer driver reports "UNSIGNED INT" etc.
+	unsigned := strings.HasPrefix(databaseTypeName, "UNSIGNED ")
+	databaseTypeName = strings.TrimPrefix(databaseTypeName, "UNSIGNED ")
+
+	switch scanType {
+	// Float types
+	case scanTypeFloat32:
+		return "float32", nil
+	case scanTypeFloat64:
+		return "float64", nil
+	case scanTypeNullFloat:
+		switch databaseTypeName {
+		case "FLOAT":
+			return "float32", nil
+		case "DOUBLE":
+			return "float64", nil
+		}
 
-		// Int types, includeing bool type
-		case scanTypeInt8:
-			if length == 1 {
-				// Special case for bool
-				dataType = "bool"
-			} else {
-				dataType = "int8"
+	// Int types
+	case scanTypeInt8:
+		return "int8", nil
+	case scanTypeInt16:
+		return "int16", nil
+	case scanTypeInt32:
+		return "int32", nil
+	case scanTypeInt64:
+		return "int64", nil
+	case scanTypeUint8:
+		return "uint8", nil
+	case scanTypeUint16:
+		return "uint16", nil
+	case scanTypeUint32:
+		return "uint32", nil
+	case scanTypeUint64:
+		return "uint64", nil
+	case scanTypeNullInt:
+		switch databaseTypeName {
+		case "TINYINT":
+			if unsigned {
+				return "uint8", nil
 			}
-		case scanTypeInt16:
-			dataType = "int16"
-		case scanTypeInt32:
-			dataType = "int32"
-		case scanTypeInt64:
-			dataType = "int64"
-		case scanTypeUint8:
-			dataType = "uint8"
-		case scanTypeUint16:
-			dataType = "uint16"
-		case scanTypeUint32:
-			dataType = "uint32"
-		case scanTypeUint64:
-			dataType = "uint64"
-		case scanTypeNullInt:
-			switch databaseTypeName {
-			case "TINYINT":
-				if isUnsigned {
-					dataType = "uint8"
-				} else {
-					if length == 1 {
-						dataType = "bool"
-					} else {
-						dataType = "int8"
-					}
-				}
-			case "SMALLINT", "YEAR":
-				if isUnsigned {
-					dataType = "uint16"
-				} else {
-					dataType = "int16"
-				}
-			case "MEDIUMINT", "INT":
-				if isUnsigned {
-					dataType = "uint32"
-				} else {
-					dataType = "int32"
-				}
-			case "BIGINT":
-				if isUnsigned {
-					dataType = "uint64"
-				} else {
-					dataType = "int64"
-				}
-			default:
-				bad()
+			return "int16", nil
+		case "SMALLINT", "YEAR":
+			if unsigned {
+				return "uint16", nil
 			}
-
-		// Time types
-		case scanTypeNullTime:
-			dataType = "time"
-
-			// String types
-		case scanTypeRawBytes:
-			switch databaseTypeName {
-			case "BIT":
-				dataType = "bit"
-			case "JSON":
-				dataType = "json"
-			default:
-				dataType = "string"
+			return "int32", nil
+		case "MEDIUMINT", "INT":
+			if unsigned {
+				return "uint32", nil
+			}
+			return "int64", nil
+		case "BIGINT":
+			if unsigned {
+				return "uint64", nil
 			}
+			return "int64", nil
+		}
 
+	// Time types
+	case scanTypeNullTime:
+		return "time", nil
+
+	// String types
+	case scanTypeRawBytes:
+		switch databaseTypeName {
+		case "BIT":
+			return "bit", nil
+		case "JSON":
+			return "json", nil
 		default:
-			bad()
+			return "string", nil
 		}
 
-		columns = append(columns, datasrc.NewColumn(columnType, dataType))
+	}
+
+	return "", fmt.Errorf("Unknown column type: scantype=%#v databaseTypeName=%+q", scanType, columnType.DatabaseTypeName())
+
+}
 
+// tableDataType determines data type of integer table columns from INFORMATION_SCHEMA.COLUMNS.COLUMN_TYPE
+// (e.g. "tinyint(1)", "int(10) unsigned"). It returns dataType as is for other types.
+func tableDataType(columnType string, dataType string) string {
+
+	columnType = strings.ToLower(columnType)
+	unsigned := strings.Contains(columnType, "unsigned")
+	baseType := columnType
+	if i := strings.IndexAny(baseType, "( "); i >= 0 {
+		baseType = baseType[:i]
 	}
 
-	return
+	bits := ""
+	switch baseType {
+	case "tinyint":
+		if strings.HasPrefix(columnType, "tinyint(1)") && !unsigned {
+			// Special case for bool
+			return "bool"
+		}
+		bits = "8"
+	case "smallint", "year":
+		bits = "16"
+	case "mediumint", "int", "integer":
+		bits = "32"
+	case "bigint":
+		bits = "64"
+	default:
+		return dataType
+	}
+
+	if unsigned {
+		return "uint" + bits
+	}
+	return "int" + bits
 
 }
 
@@ -244,7 +260,7 @@ func (driver mysqlDriver) LoadTableColumns(conn *sql.Conn, tableName string) (ta
 		return nil, err
 	}
 
-	columns, err := driver.LoadQueryResultColumns(conn, "SELECT * FROM `"+tableName+"`")
+	columns, err := driver.LoadQueryResultColumns(conn, "SELECT * FROM `"+tableName+"` LIMIT 0")
 	if err != nil {
 		return nil, err
 	}
@@ -253,7 +269,7 @@ func (driver mysqlDriver) LoadTableColumns(conn *sql.Conn, tableName string) (ta
 
 		row := conn.QueryRowContext(context.Background(), `
 		SELECT
-			IF(EXTRA='auto_increment', 'auto_increment', COLUMN_DEFAULT)
+			IF(EXTRA='auto_increment', 'auto_increment', COLUMN_DEFAULT), COLUMN_TYPE, COLUMN_COMMENT
 		FROM
 			INFORMATION_SCHEMA.COLUMNS
 		WHERE
@@ -261,14 +277,23 @@ func (driver mysqlDriver) LoadTableColumns(conn *sql.Conn, tableName string) (ta
 		`, dbName, tableName, column.Name)
 
 		defaultValue := sql.NullString{}
-		if err := row.Scan(&defaultValue); err != nil {
+		columnType := ""
+		comment := ""
+		if err := row.Scan(&defaultValue, &columnType, &comment); err != nil {
 			return nil, err
 		}
 
+		// Precise data type (e.g. bool, unsigned) from table definition.
+		column.DataType = tableDataType(columnType, column.DataType)
+
+		column.OrgTable = tableName
+		column.OrgName = column.Name
+
 		tableColumn := &datasrc.TableColumn{
 			Column:       *column,
 			Pos:          i,
 			DefaultValue: defaultValue,
+			Comment:      comment,
 		}
 
 		tableColumns = append(tableColumns, tableColumn)
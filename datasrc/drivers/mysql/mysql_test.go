@@ -126,7 +126,8 @@ func TestLoadQueryResultColumns(t *testing.T) {
 		" `blob_n_string` BLOB " +
 		")")
 
-	columns, err := driver.LoadQueryResultColumns(conn, "SELECT * FROM `types`")
+	// NOTE: Precise data types (bool, unsigned of nullable integers) are only available from table columns.
+	columns, err := driver.LoadTableColumns(conn, "types")
 	assert.NoError(err)
 	for _, column := range columns {
 		parts := strings.Split(column.Name, "_")
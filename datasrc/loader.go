@@ -82,6 +82,16 @@ func (loader *Loader) LoadQueryResultColumns(query string, args ...interface{})
 	return loader.driver.LoadQueryResultColumns(loader.conn, query, args...)
 }
 
+// ValidateQuery returns error if the query is invalid. The query is prepared on the server and then closed without
+// executing, so it has no side effects. Placeholders in query are positional.
+func (loader *Loader) ValidateQuery(query string) error {
+	stmt, err := loader.conn.PrepareContext(context.Background(), query)
+	if err != nil {
+		return err
+	}
+	return stmt.Close()
+}
+
 // LoadTableNames returns all table names in current database.
 func (loader *Loader) LoadTableNames() (tableNames []string, err error) {
 	return loader.driver.LoadTableNames(loader.conn)
@@ -37,6 +37,19 @@ type TerminalDirective interface {
 	Fragment() (string, error)
 }
 
+// Finalizer is implemented by directives which need to do some work after the whole statement is processed.
+type Finalizer interface {
+	// Finalize is called after the final statement text is constructed.
+	Finalize() error
+}
+
+// ArgDeclarer is implemented by directives declaring a wrapper function argument. Named parameters (":name")
+// in the statement text are checked against declared args.
+type ArgDeclarer interface {
+	// DeclaredArgName returns the name of the declared argument.
+	DeclaredArgName() string
+}
+
 // textDirective is a special directive.
 type textDirective struct {
 	data string
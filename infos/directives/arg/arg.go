@@ -2,6 +2,7 @@ package argdir
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/beevik/etree"
 	"github.com/huangjunwen/sqlw/datasrc"
@@ -17,12 +18,17 @@ type ArgsInfo struct {
 type ArgInfo argDirective
 
 type argDirective struct {
-	argName string
-	argType string
+	stmt      *infos.StmtInfo
+	argName   string
+	argType   string            // "" if the type is derived from argColumn
+	argColumn *infos.ColumnInfo // nil if argType is given
+	pos       infos.SourcePos
 }
 
 var (
 	_ infos.TerminalDirective = (*argDirective)(nil)
+	_ infos.ArgDeclarer       = (*argDirective)(nil)
+	_ infos.Finalizer         = (*argDirective)(nil)
 )
 
 type localsKeyType struct{}
@@ -66,11 +72,21 @@ func (info *ArgInfo) ArgName() string {
 	return info.argName
 }
 
-// ArgType returns the argument's type.
+// ArgType returns the argument's type. It returns "" if the type should be derived from ArgColumn.
 func (info *ArgInfo) ArgType() string {
 	return info.argType
 }
 
+// ArgColumn returns the table column which the argument's type derived from. It returns nil if ArgType is given.
+func (info *ArgInfo) ArgColumn() *infos.ColumnInfo {
+	return info.argColumn
+}
+
+// Pos returns the source position of the <arg> directive. It's invalid if unknown.
+func (info *ArgInfo) Pos() infos.SourcePos {
+	return info.pos
+}
+
 func (d *argDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
 
 	// Get/set ArgsInfo
@@ -87,12 +103,32 @@ func (d *argDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt
 	if argName == "" {
 		return fmt.Errorf("Missing 'name' attribute in <arg> directive")
 	}
+	d.stmt = stmt
+	d.argName = argName
+	d.pos = stmt.SourcePos(elem)
+
+	// Type can be:
+	// - type="int": explicit go type
+	// - type="@user.id" or column="user.id": derived from table column
+	// - missing: inferred from the column compared with the arg in Finalize
 	argType := elem.SelectAttrValue("type", "")
-	if argType == "" {
-		return fmt.Errorf("Missing 'type' attribute in <arg> directive")
+	columnRef := elem.SelectAttrValue("column", "")
+	switch {
+	case argType != "" && columnRef != "":
+		return fmt.Errorf("Only one of 'type' or 'column' attribute can be specified in <arg> directive")
+	case strings.HasPrefix(argType, "@"):
+		columnRef = argType[1:]
+	case argType != "":
+		d.argType = argType
+	}
+
+	if columnRef != "" {
+		column, err := lookupColumn(db, columnRef)
+		if err != nil {
+			return err
+		}
+		d.argColumn = column
 	}
-	d.argName = argName
-	d.argType = argType
 
 	// Add ArgInfo
 	info.argInfos = append(info.argInfos, (*ArgInfo)(d))
@@ -100,6 +136,22 @@ func (d *argDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt
 	return nil
 }
 
+func (d *argDirective) DeclaredArgName() string {
+	return d.argName
+}
+
+func (d *argDirective) Finalize() error {
+	if d.argType != "" || d.argColumn != nil {
+		return nil
+	}
+	d.argColumn = d.stmt.ColumnComparedWith(d.argName)
+	if d.argColumn == nil {
+		return fmt.Errorf("Can't infer type of arg %+q in statement %+q, please specify 'type' or 'column' attribute in <arg> directive",
+			d.argName, d.stmt.StmtName())
+	}
+	return nil
+}
+
 func (d *argDirective) QueryFragment() (string, error) {
 	return "", nil
 }
@@ -112,6 +164,22 @@ func (d *argDirective) Fragment() (string, error) {
 	return "", nil
 }
 
+func lookupColumn(db *infos.DBInfo, columnRef string) (*infos.ColumnInfo, error) {
+	parts := strings.Split(columnRef, ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Expect column reference in the form of \"table.column\" but got %+q", columnRef)
+	}
+	table := db.TableByName(parts[0])
+	if table == nil {
+		return nil, fmt.Errorf("Table %+q not found", parts[0])
+	}
+	column := table.ColumnByName(parts[1])
+	if column == nil {
+		return nil, fmt.Errorf("Column %+q not found in table %+q", parts[1], parts[0])
+	}
+	return column, nil
+}
+
 func init() {
 	infos.RegistDirectiveFactory(func() infos.Directive {
 		return &argDirective{}
@@ -0,0 +1,168 @@
+package coldir
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+// ColsInfo contains result column overrides in a statement.
+type ColsInfo struct {
+	stmt       *infos.StmtInfo
+	directives []*colDirective
+}
+
+type colDirective struct {
+	info     *ColsInfo
+	loader   *datasrc.Loader
+	expr     string
+	name     string
+	typ      string
+	nullable string // "" if not specified
+	marker   string
+	col      *datasrc.Column // the result column, set in ProcessQueryResultColumns
+}
+
+var (
+	_ infos.TerminalDirective = (*colDirective)(nil)
+)
+
+type localsKeyType struct{}
+
+var (
+	localsKey = localsKeyType{}
+)
+
+// ExtractColsInfo extracts result column overrides from a statement or nil if not exists.
+func ExtractColsInfo(stmt *infos.StmtInfo) *ColsInfo {
+	locals := stmt.Locals(localsKey)
+	if locals != nil {
+		return locals.(*ColsInfo)
+	}
+	return nil
+}
+
+// Valid returns true if info != nil.
+func (info *ColsInfo) Valid() bool {
+	return info != nil
+}
+
+// ColumnType returns the overridden Go type of the i-th result column or "" if not overridden.
+//
+// NOTE: The type is used as is, it must be accessible in the generated package.
+func (info *ColsInfo) ColumnType(i int) string {
+	if d := info.directive(i); d != nil {
+		return d.typ
+	}
+	return ""
+}
+
+func (info *ColsInfo) directive(i int) *colDirective {
+	if info == nil {
+		return nil
+	}
+	resultCols := info.stmt.ResultCols()
+	if i < 0 || i >= len(resultCols) {
+		return nil
+	}
+	for _, d := range info.directives {
+		if d.col == resultCols[i] {
+			return d
+		}
+	}
+	return nil
+}
+
+func (d *colDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+
+	// Get/set ColsInfo
+	locals := stmt.Locals(localsKey)
+	if locals == nil {
+		locals = &ColsInfo{
+			stmt: stmt,
+		}
+		stmt.SetLocals(localsKey, locals)
+	}
+	info := locals.(*ColsInfo)
+
+	elem := tok.(*etree.Element)
+	expr := strings.TrimSpace(elem.Text())
+	if expr == "" {
+		return fmt.Errorf("Missing expression in <col> directive")
+	}
+
+	nullable := elem.SelectAttrValue("nullable", "")
+	if nullable != "" {
+		if _, err := strconv.ParseBool(nullable); err != nil {
+			return fmt.Errorf("Invalid 'nullable' attribute %+q in <col> directive", nullable)
+		}
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+
+	d.info = info
+	d.loader = loader
+	d.expr = expr
+	d.name = elem.SelectAttrValue("name", "")
+	d.typ = elem.SelectAttrValue("type", "")
+	d.nullable = nullable
+	// NOTE: Identiy must starts with letter so add a prefix.
+	d.marker = "col" + hex.EncodeToString(buf)
+
+	info.directives = append(info.directives, d)
+	return nil
+
+}
+
+func (d *colDirective) QueryFragment() (string, error) {
+	return fmt.Sprintf("%s AS %s", d.expr, d.marker), nil
+}
+
+func (d *colDirective) ProcessQueryResultColumns(resultCols *[]*datasrc.Column) error {
+
+	for _, resultCol := range *resultCols {
+		if resultCol.Name != d.marker {
+			continue
+		}
+
+		// Restore the name.
+		resultCol.Name = d.name
+		if resultCol.Name == "" {
+			resultCol.Name = d.expr
+		}
+
+		if d.nullable != "" {
+			nullable, _ := strconv.ParseBool(d.nullable)
+			resultCol.HasNullable = true
+			resultCol.Nullable = nullable
+		}
+
+		d.col = resultCol
+		return nil
+	}
+
+	return fmt.Errorf("<col>: Result column for %+q not found", d.expr)
+
+}
+
+func (d *colDirective) Fragment() (string, error) {
+	if d.name == "" {
+		return d.expr, nil
+	}
+	return fmt.Sprintf("%s AS %s", d.expr, d.loader.Quote(d.name)), nil
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &colDirective{}
+	}, "col")
+}
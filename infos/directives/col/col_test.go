@@ -0,0 +1,64 @@
+package coldir
+
+import (
+	"testing"
+
+	"github.com/huangjunwen/sqlw/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCol(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := fakedb.NewFixture(t,
+		&fakedb.Table{
+			Name:    "payment",
+			Columns: []fakedb.Column{{Name: "id", DataType: "int32"}, {Name: "amount", DataType: "string"}},
+			Primary: []string{"id"},
+		},
+	)
+	defer fixture.Close()
+
+	{
+		stmt, err := fixture.Stmt(`<stmt name="Total">
+  SELECT id, <col name="total" type="decimal.Decimal" nullable="false">SUM(amount)</col>, <col>MAX(amount)</col> FROM payment
+</stmt>`)
+		if assert.NoError(err) {
+			assert.Equal("SELECT id, SUM(amount) AS `total`, MAX(amount) FROM payment", stmt.Text())
+
+			resultCols := stmt.ResultCols()
+			if assert.Len(resultCols, 3) {
+				// Aggregates are nullable unless overridden.
+				assert.Equal("total", resultCols[1].Name)
+				assert.True(resultCols[1].HasNullable)
+				assert.False(resultCols[1].Nullable)
+				assert.Equal("MAX(amount)", resultCols[2].Name)
+				assert.True(resultCols[2].Nullable)
+			}
+
+			info := ExtractColsInfo(stmt)
+			assert.Equal("", info.ColumnType(0))
+			assert.Equal("decimal.Decimal", info.ColumnType(1))
+			assert.Equal("", info.ColumnType(2))
+		}
+	}
+
+	for _, testCase := range []struct {
+		Stmt string
+		Err  string
+	}{
+		{
+			`<stmt name="A">SELECT <col name="total"> </col> FROM payment</stmt>`,
+			`<col>: Missing expression in <col> directive`,
+		},
+		{
+			`<stmt name="A">SELECT <col nullable="no">SUM(amount)</col> FROM payment</stmt>`,
+			`<col>: Invalid 'nullable' attribute "no" in <col> directive`,
+		},
+	} {
+		_, err := fixture.Stmt(testCase.Stmt)
+		assert.EqualError(err, testCase.Err, "Stmt: %+q", testCase.Stmt)
+	}
+
+}
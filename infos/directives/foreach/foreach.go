@@ -0,0 +1,58 @@
+package foreachdir
+
+import (
+	"fmt"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+// foreachDirective is the directive for <foreach collection="ids" item="id" separator=",">. Its content is repeated
+// for each item of the collection arg at runtime.
+type foreachDirective struct {
+	elem  *etree.Element
+	begin string
+	end   string
+}
+
+var (
+	_ infos.NonterminalDirective = (*foreachDirective)(nil)
+)
+
+func (d *foreachDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+
+	elem := tok.(*etree.Element)
+	collection := elem.SelectAttrValue("collection", "")
+	if collection == "" {
+		return fmt.Errorf("Missing 'collection' attribute in <foreach> directive")
+	}
+	item := elem.SelectAttrValue("item", "")
+	if item == "" {
+		return fmt.Errorf("Missing 'item' attribute in <foreach> directive")
+	}
+
+	d.elem = elem
+	d.begin, d.end = stmt.AddDynNode(infos.NewForeachNode(
+		collection,
+		item,
+		elem.SelectAttrValue("open", ""),
+		elem.SelectAttrValue("close", ""),
+		elem.SelectAttrValue("separator", ""),
+	))
+	return nil
+
+}
+
+func (d *foreachDirective) Expand() ([]etree.Token, error) {
+	ret := []etree.Token{etree.NewCharData(d.begin)}
+	ret = append(ret, d.elem.Child...)
+	ret = append(ret, etree.NewCharData(d.end))
+	return ret, nil
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &foreachDirective{}
+	}, "foreach")
+}
@@ -0,0 +1,122 @@
+package fragmentdir
+
+import (
+	"fmt"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+// FragmentsInfo contains reusable statement fragments defined by top level <fragment name="activeUser"> xml elements.
+// A fragment is included in statements by <include ref="activeUser" />, it may contain other directives.
+type FragmentsInfo struct {
+	fragments map[string]*etree.Element
+}
+
+// includeDirective is the directive for <include>. It expands to the content of the referenced fragment.
+type includeDirective struct {
+	fragment *etree.Element
+}
+
+var (
+	_ infos.NonterminalDirective = (*includeDirective)(nil)
+)
+
+type localsKeyType struct{}
+
+var (
+	localsKey = localsKeyType{}
+)
+
+// NewFragmentsInfo creates an empty FragmentsInfo.
+func NewFragmentsInfo() *FragmentsInfo {
+	return &FragmentsInfo{
+		fragments: map[string]*etree.Element{},
+	}
+}
+
+// Fragments returns an option of infos.NewStmtInfo, so that fragments can be included by <include> directives in
+// the statement.
+func Fragments(fragments *FragmentsInfo) infos.StmtOption {
+	return infos.Locals(localsKey, fragments)
+}
+
+// Add adds a fragment xml element.
+func (info *FragmentsInfo) Add(fragmentElem *etree.Element) error {
+
+	if fragmentElem.Tag != "fragment" {
+		return fmt.Errorf("Expect <fragment> but got <%s>", fragmentElem.Tag)
+	}
+
+	name := fragmentElem.SelectAttrValue("name", "")
+	if name == "" {
+		return fmt.Errorf("Missing 'name' attribute of <fragment>")
+	}
+	if info.fragments[name] != nil {
+		return fmt.Errorf("Duplicated fragment %+q", name)
+	}
+
+	info.fragments[name] = fragmentElem
+	return nil
+
+}
+
+// Fragment returns the named fragment xml element or nil if not found. It returns nil if info is nil.
+func (info *FragmentsInfo) Fragment(name string) *etree.Element {
+	if info == nil {
+		return nil
+	}
+	return info.fragments[name]
+}
+
+// checkCycle returns error if the named fragment includes itself directly or indirectly.
+func (info *FragmentsInfo) checkCycle(name string, visiting map[string]bool) error {
+	if visiting[name] {
+		return fmt.Errorf("Fragment %+q includes itself", name)
+	}
+	fragmentElem := info.Fragment(name)
+	if fragmentElem == nil {
+		return nil
+	}
+	visiting[name] = true
+	for _, elem := range fragmentElem.FindElements(".//include") {
+		if err := info.checkCycle(elem.SelectAttrValue("ref", ""), visiting); err != nil {
+			return err
+		}
+	}
+	delete(visiting, name)
+	return nil
+}
+
+func (d *includeDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+
+	elem := tok.(*etree.Element)
+	ref := elem.SelectAttrValue("ref", "")
+	if ref == "" {
+		return fmt.Errorf("Missing 'ref' attribute in <include> directive")
+	}
+
+	fragments, _ := stmt.Locals(localsKey).(*FragmentsInfo)
+	fragment := fragments.Fragment(ref)
+	if fragment == nil {
+		return fmt.Errorf("Fragment %+q not found in statement %+q", ref, stmt.StmtName())
+	}
+	if err := fragments.checkCycle(ref, map[string]bool{}); err != nil {
+		return err
+	}
+
+	d.fragment = fragment
+	return nil
+
+}
+
+func (d *includeDirective) Expand() ([]etree.Token, error) {
+	return d.fragment.Child, nil
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &includeDirective{}
+	}, "include")
+}
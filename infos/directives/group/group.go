@@ -0,0 +1,209 @@
+package groupdir
+
+import (
+	"fmt"
+	"go/token"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+	"github.com/huangjunwen/sqlw/infos/directives/vars"
+	"github.com/huangjunwen/sqlw/infos/directives/wc"
+)
+
+// GroupInfo contains one-to-many grouping information in a statement: rows are folded into groups
+// of parent wildcard, each group collects the child wildcards of its rows.
+type GroupInfo struct {
+	by         string
+	collect    string
+	as         string
+	typeName   string
+	keyColumns []*infos.ColumnInfo
+}
+
+type groupDirective struct {
+	stmt *infos.StmtInfo
+	info *GroupInfo
+}
+
+var (
+	_ infos.TerminalDirective = (*groupDirective)(nil)
+	_ infos.Finalizer         = (*groupDirective)(nil)
+)
+
+type localsKeyType struct{}
+
+var (
+	localsKey = localsKeyType{}
+)
+
+// ExtractGroupInfo extracts grouping information from a statement or nil if not exists.
+func ExtractGroupInfo(stmt *infos.StmtInfo) *GroupInfo {
+	locals := stmt.Locals(localsKey)
+	if locals != nil {
+		return locals.(*GroupInfo)
+	}
+	return nil
+}
+
+// Valid returns true if info != nil.
+func (info *GroupInfo) Valid() bool {
+	return info != nil
+}
+
+// By returns the parent wildcard name.
+func (info *GroupInfo) By() string {
+	if info == nil {
+		return ""
+	}
+	return info.by
+}
+
+// Collect returns the child wildcard name.
+func (info *GroupInfo) Collect() string {
+	if info == nil {
+		return ""
+	}
+	return info.collect
+}
+
+// As returns the name of the collection. It's the child wildcard name if not specified.
+func (info *GroupInfo) As() string {
+	if info == nil {
+		return ""
+	}
+	if info.as != "" {
+		return info.as
+	}
+	return info.collect
+}
+
+// TypeName returns the Go type name of groups. It's the statement name with "Group" suffix if not specified.
+func (info *GroupInfo) TypeName() string {
+	if info == nil {
+		return ""
+	}
+	return info.typeName
+}
+
+// KeyColumns returns the parent table's primary key columns, which are used to deduplicate parents.
+func (info *GroupInfo) KeyColumns() []*infos.ColumnInfo {
+	if info == nil {
+		return nil
+	}
+	return info.keyColumns
+}
+
+func (d *groupDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+
+	if stmt.Locals(localsKey) != nil {
+		return fmt.Errorf("Multiple <group> directives in statement %+q", stmt.StmtName())
+	}
+
+	elem := tok.(*etree.Element)
+	by := elem.SelectAttrValue("by", "")
+	if by == "" {
+		return fmt.Errorf("Missing 'by' attribute in <group> directive")
+	}
+	collect := elem.SelectAttrValue("collect", "")
+	if collect == "" {
+		return fmt.Errorf("Missing 'collect' attribute in <group> directive")
+	}
+	if by == collect {
+		return fmt.Errorf("'by' and 'collect' attributes should be different in <group> directive")
+	}
+	typeName := elem.SelectAttrValue("type", stmt.StmtName()+"Group")
+	if !token.IsIdentifier(typeName) {
+		return fmt.Errorf("Invalid 'type' attribute %+q in <group> directive", typeName)
+	}
+
+	d.stmt = stmt
+	d.info = &GroupInfo{
+		by:       by,
+		collect:  collect,
+		as:       elem.SelectAttrValue("as", ""),
+		typeName: typeName,
+	}
+	stmt.SetLocals(localsKey, d.info)
+	return nil
+
+}
+
+func (d *groupDirective) QueryFragment() (string, error) {
+	return "", nil
+}
+
+func (d *groupDirective) ProcessQueryResultColumns(resultCols *[]*datasrc.Column) error {
+	return nil
+}
+
+func (d *groupDirective) Fragment() (string, error) {
+	return "", nil
+}
+
+// Finalize checks that result columns are all from the parent and child wildcards and
+// that the parent wildcard contains its table's primary key.
+func (d *groupDirective) Finalize() error {
+
+	stmt := d.stmt
+	info := d.info
+
+	if stmt.StmtType() != "SELECT" {
+		return fmt.Errorf("<group> directive can only be used in SELECT statement but %+q is %s",
+			stmt.StmtName(), stmt.StmtType())
+	}
+
+	if varsdir.ExtractVarsInfo(stmt).Has("return") {
+		return fmt.Errorf("<group> directive can't be used with 'return' var in statement %+q", stmt.StmtName())
+	}
+
+	wildcards := wcdir.ExtractWildcardsInfo(stmt)
+	var parent, child *infos.TableInfo
+	parentColumns := map[*infos.ColumnInfo]bool{}
+
+	for i, resultCol := range stmt.ResultCols() {
+		switch wildcards.WildcardName(i) {
+		case info.by:
+			if wildcards.WildcardNullable(i) {
+				return fmt.Errorf("<group>: Parent wildcard %+q can't be nullable", info.by)
+			}
+			parent = wildcards.WildcardColumn(i).Table()
+			parentColumns[wildcards.WildcardColumn(i)] = true
+
+		case info.collect:
+			child = wildcards.WildcardColumn(i).Table()
+
+		default:
+			return fmt.Errorf("<group>: Result column %+q is neither from wildcard %+q nor %+q",
+				resultCol.Name, info.by, info.collect)
+		}
+	}
+
+	if parent == nil {
+		return fmt.Errorf("<group>: Parent wildcard %+q not found in statement %+q", info.by, stmt.StmtName())
+	}
+	if child == nil {
+		return fmt.Errorf("<group>: Child wildcard %+q not found in statement %+q", info.collect, stmt.StmtName())
+	}
+
+	primary := parent.Primary()
+	if !primary.Valid() {
+		return fmt.Errorf("<group>: Parent table %s has no primary key", parent.String())
+	}
+	for _, column := range primary.Columns() {
+		if !parentColumns[column] {
+			return fmt.Errorf("<group>: Primary key column %+q is not selected in parent wildcard %+q",
+				column.ColumnName(), info.by)
+		}
+	}
+	info.keyColumns = primary.Columns()
+
+	return nil
+
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &groupDirective{}
+	}, "group")
+}
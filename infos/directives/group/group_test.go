@@ -0,0 +1,95 @@
+package groupdir
+
+import (
+	"testing"
+
+	"github.com/huangjunwen/sqlw/infos"
+	"github.com/huangjunwen/sqlw/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := fakedb.NewFixture(t,
+		&fakedb.Table{
+			Name:    "user",
+			Columns: []fakedb.Column{{Name: "id", DataType: "int32"}, {Name: "name", DataType: "string"}},
+			Primary: []string{"id"},
+		},
+		&fakedb.Table{
+			Name:    "blog",
+			Columns: []fakedb.Column{{Name: "id", DataType: "int32"}, {Name: "user_id", DataType: "int32"}, {Name: "title", DataType: "string"}},
+			Primary: []string{"id"},
+		},
+		&fakedb.Table{
+			Name:    "tag",
+			Columns: []fakedb.Column{{Name: "name", DataType: "string"}, {Name: "blog_id", DataType: "int32"}},
+		},
+	)
+	defer fixture.Close()
+
+	// One-to-many.
+	{
+		stmt, err := fixture.Stmt(`<stmt name="UsersWithBlogs">
+  <group by="user" collect="blog" as="blogs" />
+  SELECT <wc table="user" />, <wc table="blog" nullable="true" /> FROM user LEFT JOIN blog ON blog.user_id=user.id
+</stmt>`)
+		if assert.NoError(err) {
+			info := ExtractGroupInfo(stmt)
+			assert.True(info.Valid())
+			assert.Equal("user", info.By())
+			assert.Equal("blog", info.Collect())
+			assert.Equal("blogs", info.As())
+			assert.Equal("UsersWithBlogsGroup", info.TypeName())
+			assert.Equal([]*infos.ColumnInfo{fixture.DB.TableByName("user").ColumnByName("id")}, info.KeyColumns())
+		}
+	}
+
+	// Explicit type name, collection name defaults to the child wildcard name.
+	{
+		stmt, err := fixture.Stmt(`<stmt name="UserNamesWithTitles">
+  <group by="u" collect="b" type="UserTitles" />
+  SELECT <wc table="user" as="u" columns="id,name" partial="true" />, <wc table="blog" as="b" columns="title" partial="true" /> FROM user u JOIN blog b ON b.user_id=u.id
+</stmt>`)
+		if assert.NoError(err) {
+			info := ExtractGroupInfo(stmt)
+			assert.Equal("b", info.As())
+			assert.Equal("UserTitles", info.TypeName())
+		}
+	}
+
+	// Errors.
+	for _, testCase := range []struct {
+		Stmt string
+		Err  string
+	}{
+		{
+			`<stmt name="A"><group by="tag" collect="blog" />SELECT <wc table="tag" />, <wc table="blog" /> FROM tag JOIN blog ON tag.blog_id=blog.id</stmt>`,
+			"Parent table tag has no primary key",
+		},
+		{
+			`<stmt name="A"><group by="user" collect="blog" />SELECT <wc table="user" columns="name" />, <wc table="blog" /> FROM user JOIN blog ON blog.user_id=user.id</stmt>`,
+			"Primary key column \"id\" is not selected",
+		},
+		{
+			`<stmt name="A"><group by="user" collect="blog" />SELECT <wc table="user" />, <wc table="blog" />, 1 AS one FROM user JOIN blog ON blog.user_id=user.id</stmt>`,
+			"Result column \"one\" is neither from wildcard",
+		},
+		{
+			`<stmt name="A"><group by="user" collect="blog" />SELECT <wc table="user" /> FROM user</stmt>`,
+			"Child wildcard \"blog\" not found",
+		},
+		{
+			`<stmt name="A"><group by="user" collect="blog" type="a.b" />SELECT <wc table="user" />, <wc table="blog" /> FROM user JOIN blog ON blog.user_id=user.id</stmt>`,
+			"Invalid 'type' attribute",
+		},
+	} {
+		_, err := fixture.Stmt(testCase.Stmt)
+		if assert.Error(err, "Statement: %s", testCase.Stmt) {
+			assert.Contains(err.Error(), testCase.Err, "Statement: %s", testCase.Stmt)
+		}
+	}
+
+}
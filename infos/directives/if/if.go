@@ -0,0 +1,52 @@
+package ifdir
+
+import (
+	"fmt"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+// ifDirective is the directive for <if test="title != nil">. Its content is included only if the test is true
+// at runtime.
+type ifDirective struct {
+	elem  *etree.Element
+	begin string
+	end   string
+}
+
+var (
+	_ infos.NonterminalDirective = (*ifDirective)(nil)
+)
+
+func (d *ifDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+
+	elem := tok.(*etree.Element)
+	test := elem.SelectAttrValue("test", "")
+	if test == "" {
+		return fmt.Errorf("Missing 'test' attribute in <if> directive")
+	}
+	node, err := infos.NewIfNode(test)
+	if err != nil {
+		return fmt.Errorf("Invalid 'test' attribute %+q in <if> directive: %s", test, err)
+	}
+
+	d.elem = elem
+	d.begin, d.end = stmt.AddDynNode(node)
+	return nil
+
+}
+
+func (d *ifDirective) Expand() ([]etree.Token, error) {
+	ret := []etree.Token{etree.NewCharData(d.begin)}
+	ret = append(ret, d.elem.Child...)
+	ret = append(ret, etree.NewCharData(d.end))
+	return ret, nil
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &ifDirective{}
+	}, "if")
+}
@@ -0,0 +1,89 @@
+package indir
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+	"github.com/huangjunwen/sqlw/infos/directives/arg"
+)
+
+// inDirective is the directive for <in arg="ids">id</in>. It expands a slice arg to an IN-list at runtime.
+type inDirective struct {
+	stmt       *infos.StmtInfo
+	collection string
+	expr       string
+	begin      string
+	end        string
+}
+
+var (
+	_ infos.TerminalDirective = (*inDirective)(nil)
+	_ infos.Finalizer         = (*inDirective)(nil)
+)
+
+func (d *inDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+
+	elem := tok.(*etree.Element)
+	collection := elem.SelectAttrValue("arg", "")
+	if collection == "" {
+		return fmt.Errorf("Missing 'arg' attribute in <in> directive")
+	}
+	expr := strings.TrimSpace(elem.Text())
+	empty := elem.SelectAttrValue("empty", "error")
+
+	switch empty {
+	case "error", "false":
+	case "skip":
+		if expr == "" {
+			return fmt.Errorf("<in> directive without expression can't skip empty slice")
+		}
+	default:
+		return fmt.Errorf("Invalid 'empty' attribute %+q in <in> directive", empty)
+	}
+
+	d.stmt = stmt
+	d.collection = collection
+	d.expr = expr
+	d.begin, d.end = stmt.AddDynNode(infos.NewInNode(collection, expr, empty))
+	return nil
+
+}
+
+func (d *inDirective) QueryFragment() (string, error) {
+	// NOTE: "x IN (NULL)" is always valid and never true.
+	query := "(NULL)"
+	if d.expr != "" {
+		query = d.expr + " IN (NULL)"
+	}
+	return d.begin + query + d.end, nil
+}
+
+func (d *inDirective) ProcessQueryResultColumns(resultCols *[]*datasrc.Column) error {
+	return nil
+}
+
+func (d *inDirective) Fragment() (string, error) {
+	// NOTE: The IN-list is generated from node attributes.
+	return d.begin + d.end, nil
+}
+
+// Finalize checks that the arg is a slice. Undeclared arg is reported by named parameter checking.
+func (d *inDirective) Finalize() error {
+
+	for _, arg := range argdir.ExtractArgsInfo(d.stmt).Args() {
+		if arg.ArgName() == d.collection && !strings.HasPrefix(arg.ArgType(), "[]") {
+			return fmt.Errorf("<in> directive expects a slice arg but %+q is not a slice in statement %+q", d.collection, d.stmt.StmtName())
+		}
+	}
+	return nil
+
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &inDirective{}
+	}, "in")
+}
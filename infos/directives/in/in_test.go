@@ -0,0 +1,65 @@
+package indir
+
+import (
+	"testing"
+
+	"github.com/huangjunwen/sqlw/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIn(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := fakedb.NewFixture(t,
+		&fakedb.Table{
+			Name:    "user",
+			Columns: []fakedb.Column{{Name: "id", DataType: "int32"}, {Name: "name", DataType: "string"}},
+			Primary: []string{"id"},
+		},
+	)
+	defer fixture.Close()
+
+	{
+		stmt, err := fixture.Stmt(`<stmt name="Users">
+  <arg name="ids" type="[]int" />
+  SELECT id, name FROM user WHERE <in arg="ids">id</in>
+</stmt>`)
+		if assert.NoError(err) {
+			assert.True(stmt.IsDynamic())
+			nodes := stmt.DynNodes()
+			if assert.Len(nodes, 2) {
+				assert.Equal("in", nodes[1].Kind())
+				assert.Equal("ids", nodes[1].Collection())
+				assert.Equal("id", nodes[1].Expr())
+				assert.Equal("error", nodes[1].Empty())
+			}
+		}
+	}
+
+	for _, testCase := range []struct {
+		Stmt string
+		Err  string
+	}{
+		{
+			`<stmt name="Users"><arg name="id" type="int" />SELECT id FROM user WHERE <in arg="id">id</in></stmt>`,
+			`<in>: <in> directive expects a slice arg but "id" is not a slice in statement "Users"`,
+		},
+		{
+			`<stmt name="Users"><arg name="id" type="@user.id" />SELECT id FROM user WHERE <in arg="id">id</in></stmt>`,
+			`<in>: <in> directive expects a slice arg but "id" is not a slice in statement "Users"`,
+		},
+		{
+			`<stmt name="Users">SELECT id FROM user WHERE <in arg="ids">id</in></stmt>`,
+			`<stmt>: Undeclared arg "ids" in <in> directive in statement "Users"`,
+		},
+		{
+			`<stmt name="Users"><arg name="ids" type="[]int" />SELECT id FROM user WHERE <in arg="ids" empty="skip" /></stmt>`,
+			`<in>: <in> directive without expression can't skip empty slice`,
+		},
+	} {
+		_, err := fixture.Stmt(testCase.Stmt)
+		assert.EqualError(err, testCase.Err)
+	}
+
+}
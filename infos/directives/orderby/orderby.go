@@ -0,0 +1,248 @@
+package orderbydir
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+	"github.com/huangjunwen/sqlw/infos/directives/arg"
+	"github.com/huangjunwen/sqlw/infos/directives/paginate"
+	"github.com/huangjunwen/sqlw/infos/directives/vars"
+)
+
+const (
+	// SortArgName is the name of the sort argument of wrapper function.
+	SortArgName = "sort"
+)
+
+var (
+	// Only plain column references ("col" or "tbl.col") are allowed.
+	sortColumnRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+)
+
+// OrderByInfo contains dynamic ORDER BY information in a statement. Each sort is rendered
+// to a separated statement text so that no string concatenation is needed at runtime.
+type OrderByInfo struct {
+	marker string
+	sorts  []*SortInfo
+}
+
+// SortInfo is one of the sort orders. The first one is always the default sort order.
+type SortInfo struct {
+	key    string
+	column string
+	desc   bool
+}
+
+type orderByDirective struct {
+	loader *datasrc.Loader
+	stmt   *infos.StmtInfo
+	info   *OrderByInfo
+}
+
+var (
+	_ infos.TerminalDirective = (*orderByDirective)(nil)
+	_ infos.Finalizer         = (*orderByDirective)(nil)
+)
+
+type localsKeyType struct{}
+
+var (
+	localsKey = localsKeyType{}
+)
+
+// ExtractOrderByInfo extracts dynamic ORDER BY information from a statement or nil if not exists.
+func ExtractOrderByInfo(stmt *infos.StmtInfo) *OrderByInfo {
+	locals := stmt.Locals(localsKey)
+	if locals != nil {
+		return locals.(*OrderByInfo)
+	}
+	return nil
+}
+
+// Valid returns true if info != nil.
+func (info *OrderByInfo) Valid() bool {
+	return info != nil
+}
+
+// SortArg returns the name of the sort argument.
+func (info *OrderByInfo) SortArg() string {
+	if info == nil {
+		return ""
+	}
+	return SortArgName
+}
+
+// Sorts returns all sort orders: the default one first, then ascending and descending order
+// of each allowed column.
+func (info *OrderByInfo) Sorts() []*SortInfo {
+	if info == nil {
+		return nil
+	}
+	return info.sorts
+}
+
+// Variants returns text with ORDER BY clause of each sort order, in the same order as Sorts.
+// text should be the statement text or positional text.
+func (info *OrderByInfo) Variants(text string) []string {
+	if info == nil {
+		return nil
+	}
+	ret := []string{}
+	for _, sort := range info.sorts {
+		ret = append(ret, strings.Replace(text, info.marker, sort.clause(), -1))
+	}
+	return ret
+}
+
+// IsDefault returns true if this is the default sort order.
+func (sort *SortInfo) IsDefault() bool {
+	return sort.key == ""
+}
+
+// Key returns the key of the sort order: "col" for ascending order and "-col" for descending order.
+// It returns "" for the default sort order.
+func (sort *SortInfo) Key() string {
+	return sort.key
+}
+
+// Column returns the sort column.
+func (sort *SortInfo) Column() string {
+	return sort.column
+}
+
+// Desc returns true if it's descending order.
+func (sort *SortInfo) Desc() bool {
+	return sort.desc
+}
+
+func (sort *SortInfo) clause() string {
+	if sort.desc {
+		return fmt.Sprintf("ORDER BY %s DESC", sort.column)
+	}
+	return fmt.Sprintf("ORDER BY %s", sort.column)
+}
+
+func (d *orderByDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+
+	if stmt.Locals(localsKey) != nil {
+		return fmt.Errorf("Multiple <orderby> directives in statement %+q", stmt.StmtName())
+	}
+
+	elem := tok.(*etree.Element)
+	allowed := elem.SelectAttrValue("allowed", "")
+	if allowed == "" {
+		return fmt.Errorf("Missing 'allowed' attribute in <orderby> directive")
+	}
+	dft := elem.SelectAttrValue("default", "")
+	if dft == "" {
+		return fmt.Errorf("Missing 'default' attribute in <orderby> directive")
+	}
+
+	// The default sort order, "-col" for descending order.
+	sorts := []*SortInfo{
+		{
+			column: strings.TrimPrefix(dft, "-"),
+			desc:   strings.HasPrefix(dft, "-"),
+		},
+	}
+
+	dftAllowed := false
+	for _, column := range strings.Split(allowed, ",") {
+		column = strings.TrimSpace(column)
+		if !sortColumnRe.MatchString(column) {
+			return fmt.Errorf("Invalid column %+q in 'allowed' attribute of <orderby> directive", column)
+		}
+		for _, sort := range sorts[1:] {
+			if sort.column == column {
+				return fmt.Errorf("Duplicated column %+q in 'allowed' attribute of <orderby> directive", column)
+			}
+		}
+		if column == sorts[0].column {
+			dftAllowed = true
+		}
+		sorts = append(sorts, &SortInfo{
+			key:    column,
+			column: column,
+		}, &SortInfo{
+			key:    "-" + column,
+			column: column,
+			desc:   true,
+		})
+	}
+	if !dftAllowed {
+		return fmt.Errorf("Default column %+q is not in 'allowed' attribute of <orderby> directive", sorts[0].column)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+
+	d.loader = loader
+	d.stmt = stmt
+	d.info = &OrderByInfo{
+		marker: "orderby" + hex.EncodeToString(buf),
+		sorts:  sorts,
+	}
+	stmt.SetLocals(localsKey, d.info)
+	return nil
+
+}
+
+func (d *orderByDirective) QueryFragment() (string, error) {
+	return d.info.sorts[0].clause(), nil
+}
+
+func (d *orderByDirective) ProcessQueryResultColumns(resultCols *[]*datasrc.Column) error {
+	return nil
+}
+
+func (d *orderByDirective) Fragment() (string, error) {
+	// NOTE: The marker is replaced in Variants.
+	return d.info.marker, nil
+}
+
+// Finalize checks conflicts with other directives and validates allowed columns.
+func (d *orderByDirective) Finalize() error {
+
+	stmt := d.stmt
+
+	if varsdir.ExtractVarsInfo(stmt).Has("use_template") {
+		return fmt.Errorf("<orderby> directive can't be used with 'use_template' var in statement %+q", stmt.StmtName())
+	}
+	if paginatedir.ExtractPaginateInfo(stmt).Valid() {
+		return fmt.Errorf("<orderby> directive can't be used with <paginate> directive in statement %+q", stmt.StmtName())
+	}
+	if stmt.IsDynamic() {
+		return fmt.Errorf("<orderby> directive can't be used in dynamic statement %+q", stmt.StmtName())
+	}
+
+	for _, arg := range argdir.ExtractArgsInfo(stmt).Args() {
+		if arg.ArgName() == SortArgName {
+			return fmt.Errorf("<orderby>: Arg name %+q is reserved for sort in statement %+q", SortArgName, stmt.StmtName())
+		}
+	}
+
+	// Only the default sort order is in the query loaded from database, validate the others (one for each column).
+	variants := d.info.Variants(stmt.PositionalText())
+	for i := 1; i < len(variants); i += 2 {
+		if err := d.loader.ValidateQuery(variants[i]); err != nil {
+			return fmt.Errorf("Invalid column %+q in 'allowed' attribute of <orderby> directive: %s", d.info.sorts[i].column, err)
+		}
+	}
+
+	return nil
+
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &orderByDirective{}
+	}, "orderby")
+}
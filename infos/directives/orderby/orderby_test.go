@@ -0,0 +1,53 @@
+package orderbydir
+
+import (
+	"testing"
+
+	"github.com/huangjunwen/sqlw/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderBy(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := fakedb.NewFixture(t,
+		&fakedb.Table{
+			Name:    "user",
+			Columns: []fakedb.Column{{Name: "id", DataType: "int32"}, {Name: "name", DataType: "string"}},
+			Primary: []string{"id"},
+		},
+	)
+	defer fixture.Close()
+
+	{
+		stmt, err := fixture.Stmt(`<stmt name="Users">
+  SELECT id, name FROM user u <orderby allowed="u.name,id" default="-id" />
+</stmt>`)
+		if assert.NoError(err) {
+			info := ExtractOrderByInfo(stmt)
+			assert.True(info.Valid())
+			keys := []string{}
+			for _, sort := range info.Sorts() {
+				keys = append(keys, sort.Key())
+			}
+			assert.Equal([]string{"", "u.name", "-u.name", "id", "-id"}, keys)
+			assert.Equal([]string{
+				"SELECT id, name FROM user u ORDER BY id DESC",
+				"SELECT id, name FROM user u ORDER BY u.name",
+				"SELECT id, name FROM user u ORDER BY u.name DESC",
+				"SELECT id, name FROM user u ORDER BY id",
+				"SELECT id, name FROM user u ORDER BY id DESC",
+			}, info.Variants(stmt.PositionalText()))
+		}
+	}
+
+	// Every allowed column is validated, not only the default one.
+	{
+		_, err := fixture.Stmt(`<stmt name="Users">
+  SELECT id, name FROM user <orderby allowed="id,nickname" default="id" />
+</stmt>`)
+		assert.EqualError(err, `<orderby>: Invalid column "nickname" in 'allowed' attribute of <orderby> directive: Unknown column "nickname" in 'order clause'`)
+	}
+
+}
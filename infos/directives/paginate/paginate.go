@@ -0,0 +1,259 @@
+package paginatedir
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+	"github.com/huangjunwen/sqlw/infos/directives/arg"
+	"github.com/huangjunwen/sqlw/infos/directives/group"
+	"github.com/huangjunwen/sqlw/infos/directives/vars"
+	"github.com/huangjunwen/sqlw/infos/directives/wc"
+)
+
+const (
+	// CursorArgName is the name of the cursor argument of paginated wrapper function.
+	CursorArgName = "cursor"
+)
+
+var (
+	// The seek predicate must be a condition of WHERE clause: "WHERE <paginate />" or "WHERE ... AND <paginate />".
+	predicatePosRe = regexp.MustCompile(`(?i)\b(WHERE|AND)\s*$`)
+)
+
+// PaginateInfo contains keyset pagination information in a statement.
+type PaginateInfo struct {
+	keys       []paginateKey
+	sizeArg    string
+	keyIndices []int
+}
+
+type paginateKey struct {
+	expr string // key expression, also used to find the result column
+	desc bool
+}
+
+type paginateDirective struct {
+	stmt *infos.StmtInfo
+	info *PaginateInfo
+}
+
+var (
+	_ infos.TerminalDirective = (*paginateDirective)(nil)
+	_ infos.Finalizer         = (*paginateDirective)(nil)
+	_ infos.ArgDeclarer       = (*paginateDirective)(nil)
+)
+
+type localsKeyType struct{}
+
+var (
+	localsKey = localsKeyType{}
+)
+
+// ExtractPaginateInfo extracts pagination information from a statement or nil if not exists.
+func ExtractPaginateInfo(stmt *infos.StmtInfo) *PaginateInfo {
+	locals := stmt.Locals(localsKey)
+	if locals != nil {
+		return locals.(*PaginateInfo)
+	}
+	return nil
+}
+
+// Valid returns true if info != nil.
+func (info *PaginateInfo) Valid() bool {
+	return info != nil
+}
+
+// SizeArg returns the name of the page size argument.
+func (info *PaginateInfo) SizeArg() string {
+	if info == nil {
+		return ""
+	}
+	return info.sizeArg
+}
+
+// CursorArg returns the name of the cursor argument.
+func (info *PaginateInfo) CursorArg() string {
+	if info == nil {
+		return ""
+	}
+	return CursorArgName
+}
+
+// KeyIndices returns the result column indices of pagination keys.
+func (info *PaginateInfo) KeyIndices() []int {
+	if info == nil {
+		return nil
+	}
+	return info.keyIndices
+}
+
+func (d *paginateDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+
+	if stmt.Locals(localsKey) != nil {
+		return fmt.Errorf("Multiple <paginate> directives in statement %+q", stmt.StmtName())
+	}
+
+	elem := tok.(*etree.Element)
+	by := elem.SelectAttrValue("by", "")
+	if by == "" {
+		return fmt.Errorf("Missing 'by' attribute in <paginate> directive")
+	}
+	sizeArg := elem.SelectAttrValue("size-arg", "")
+	if sizeArg == "" {
+		return fmt.Errorf("Missing 'size-arg' attribute in <paginate> directive")
+	}
+
+	// "-key" for descending order.
+	keys := []paginateKey{}
+	for _, expr := range strings.Split(by, ",") {
+		expr = strings.TrimSpace(expr)
+		key := paginateKey{
+			expr: strings.TrimPrefix(expr, "-"),
+			desc: strings.HasPrefix(expr, "-"),
+		}
+		if key.expr == "" {
+			return fmt.Errorf("Invalid 'by' attribute %+q in <paginate> directive", by)
+		}
+		keys = append(keys, key)
+	}
+
+	d.stmt = stmt
+	d.info = &PaginateInfo{
+		keys:    keys,
+		sizeArg: sizeArg,
+	}
+	stmt.SetLocals(localsKey, d.info)
+	return nil
+
+}
+
+func (d *paginateDirective) DeclaredArgName() string {
+	return CursorArgName
+}
+
+func (d *paginateDirective) orderBy() string {
+	exprs := []string{}
+	for _, key := range d.info.keys {
+		expr := key.expr
+		if key.desc {
+			expr += " DESC"
+		}
+		exprs = append(exprs, expr)
+	}
+	return "ORDER BY " + strings.Join(exprs, ", ")
+}
+
+// seek returns the seek predicate, e.g. "(NOT :cursor.valid OR a > :cursor.k0 OR (a = :cursor.k0 AND b > :cursor.k1))"
+// for keys (a, b). Row constructor comparison "(a, b) > (x, y)" is not used since it can't express mixed directions.
+func (d *paginateDirective) seek() string {
+	ors := []string{fmt.Sprintf("NOT :%s.valid", CursorArgName)}
+	for i, key := range d.info.keys {
+		ands := []string{}
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = :%s.k%d", d.info.keys[j].expr, CursorArgName, j))
+		}
+		op := ">"
+		if key.desc {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s :%s.k%d", key.expr, op, CursorArgName, i))
+		if len(ands) == 1 {
+			ors = append(ors, ands[0])
+		} else {
+			ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		}
+	}
+	return "(" + strings.Join(ors, " OR ") + ")"
+}
+
+func (d *paginateDirective) QueryFragment() (string, error) {
+	return "1 " + d.orderBy(), nil
+}
+
+func (d *paginateDirective) ProcessQueryResultColumns(resultCols *[]*datasrc.Column) error {
+	return nil
+}
+
+func (d *paginateDirective) Fragment() (string, error) {
+	return fmt.Sprintf("%s %s LIMIT :%s", d.seek(), d.orderBy(), d.info.sizeArg), nil
+}
+
+// Finalize resolves keys to result columns and checks conflicts with other directives.
+func (d *paginateDirective) Finalize() error {
+
+	stmt := d.stmt
+	info := d.info
+
+	if stmt.StmtType() != "SELECT" {
+		return fmt.Errorf("<paginate> directive can only be used in SELECT statement but %+q is %s",
+			stmt.StmtName(), stmt.StmtType())
+	}
+
+	vars := varsdir.ExtractVarsInfo(stmt)
+	if vars.Has("return") {
+		return fmt.Errorf("<paginate> directive can't be used with 'return' var in statement %+q", stmt.StmtName())
+	}
+	if vars.Has("use_template") {
+		return fmt.Errorf("<paginate> directive can't be used with 'use_template' var in statement %+q", stmt.StmtName())
+	}
+	if groupdir.ExtractGroupInfo(stmt).Valid() {
+		return fmt.Errorf("<paginate> directive can't be used with <group> directive in statement %+q", stmt.StmtName())
+	}
+	if stmt.IsDynamic() {
+		return fmt.Errorf("<paginate> directive can't be used in dynamic statement %+q", stmt.StmtName())
+	}
+
+	// It's followed by ORDER BY and LIMIT clauses, so it must be at the end of statement.
+	text := stmt.Text()
+	fragment, _ := d.Fragment()
+	i := strings.Index(text, fragment)
+	if i < 0 || !predicatePosRe.MatchString(text[:i]) || strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text[i+len(fragment):]), ";")) != "" {
+		return fmt.Errorf("<paginate> directive must follow 'WHERE' or 'AND' at the end of statement %+q", stmt.StmtName())
+	}
+
+	sizeArgFound := false
+	for _, arg := range argdir.ExtractArgsInfo(stmt).Args() {
+		switch arg.ArgName() {
+		case info.sizeArg:
+			sizeArgFound = true
+		case CursorArgName:
+			return fmt.Errorf("<paginate>: Arg name %+q is reserved for cursor in statement %+q", CursorArgName, stmt.StmtName())
+		}
+	}
+	if !sizeArgFound {
+		return fmt.Errorf("<paginate>: Size arg %+q is not declared in statement %+q", info.sizeArg, stmt.StmtName())
+	}
+
+	// Keys must be non-nullable result columns to construct next cursor.
+	wildcards := wcdir.ExtractWildcardsInfo(stmt)
+	keyIndices := []int{}
+	for _, key := range info.keys {
+		i, err := wcdir.ResultColIndex(stmt, key.expr)
+		if err != nil && strings.Contains(key.expr, ".") {
+			// Try "tbl.col" as "col".
+			i, err = wcdir.ResultColIndex(stmt, key.expr[strings.LastIndex(key.expr, ".")+1:])
+		}
+		if err != nil {
+			return fmt.Errorf("<paginate>: %s", err)
+		}
+		resultCol := stmt.ResultCols()[i]
+		if wildcards.WildcardNullable(i) || (resultCol.HasNullable && resultCol.Nullable) {
+			return fmt.Errorf("<paginate>: Key %+q is nullable in statement %+q", key.expr, stmt.StmtName())
+		}
+		keyIndices = append(keyIndices, i)
+	}
+	info.keyIndices = keyIndices
+
+	return nil
+
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &paginateDirective{}
+	}, "paginate")
+}
@@ -0,0 +1,62 @@
+package paginatedir
+
+import (
+	"testing"
+
+	"github.com/huangjunwen/sqlw/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginate(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := fakedb.NewFixture(t,
+		&fakedb.Table{
+			Name:    "user",
+			Columns: []fakedb.Column{{Name: "id", DataType: "int32"}, {Name: "name", DataType: "string"}, {Name: "email", DataType: "string", Nullable: true}},
+			Primary: []string{"id"},
+		},
+	)
+	defer fixture.Close()
+
+	{
+		stmt, err := fixture.Stmt(`<stmt name="Users">
+  <arg name="limit" type="int" />
+  SELECT id, name FROM user WHERE name != '' AND <paginate by="-name,id" size-arg="limit" />
+</stmt>`)
+		if assert.NoError(err) {
+			info := ExtractPaginateInfo(stmt)
+			assert.True(info.Valid())
+			assert.Equal("limit", info.SizeArg())
+			assert.Equal([]int{1, 0}, info.KeyIndices())
+			assert.Equal("SELECT id, name FROM user WHERE name != '' AND (NOT :cursor.valid OR name < :cursor.k0 OR (name = :cursor.k0 AND id > :cursor.k1)) ORDER BY name DESC, id LIMIT :limit", stmt.Text())
+		}
+	}
+
+	for _, testCase := range []struct {
+		Stmt string
+		Err  string
+	}{
+		{
+			`<stmt name="Users"><arg name="limit" type="int" />SELECT id FROM user WHERE <paginate by="id" size-arg="limit" /> AND name != ''</stmt>`,
+			`<paginate>: <paginate> directive must follow 'WHERE' or 'AND' at the end of statement "Users"`,
+		},
+		{
+			`<stmt name="Users"><arg name="limit" type="int" />SELECT id FROM user WHERE name != '' OR <paginate by="id" size-arg="limit" /></stmt>`,
+			`<paginate>: <paginate> directive must follow 'WHERE' or 'AND' at the end of statement "Users"`,
+		},
+		{
+			`<stmt name="Users"><arg name="limit" type="int" /><arg name="cursor" type="int" />SELECT id FROM user WHERE <paginate by="id" size-arg="limit" /></stmt>`,
+			`<paginate>: <paginate>: Arg name "cursor" is reserved for cursor in statement "Users"`,
+		},
+		{
+			`<stmt name="Users"><arg name="limit" type="int" />SELECT id, email FROM user WHERE <paginate by="email,id" size-arg="limit" /></stmt>`,
+			`<paginate>: <paginate>: Key "email" is nullable in statement "Users"`,
+		},
+	} {
+		_, err := fixture.Stmt(testCase.Stmt)
+		assert.EqualError(err, testCase.Err)
+	}
+
+}
@@ -0,0 +1,38 @@
+package setdir
+
+import (
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+// setDirective is the directive for <set>. Trailing "," of its content is stripped and "SET" is prepended if
+// the content is not empty at runtime.
+type setDirective struct {
+	elem  *etree.Element
+	begin string
+	end   string
+}
+
+var (
+	_ infos.NonterminalDirective = (*setDirective)(nil)
+)
+
+func (d *setDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+	d.elem = tok.(*etree.Element)
+	d.begin, d.end = stmt.AddDynNode(infos.NewSetNode())
+	return nil
+}
+
+func (d *setDirective) Expand() ([]etree.Token, error) {
+	ret := []etree.Token{etree.NewCharData(d.begin)}
+	ret = append(ret, d.elem.Child...)
+	ret = append(ret, etree.NewCharData(d.end))
+	return ret, nil
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &setDirective{}
+	}, "set")
+}
@@ -1,6 +1,8 @@
 package varsdir
 
 import (
+	"fmt"
+
 	"github.com/beevik/etree"
 	"github.com/huangjunwen/sqlw/datasrc"
 	"github.com/huangjunwen/sqlw/infos"
@@ -69,6 +71,9 @@ func (d *varsDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stm
 	// Add vars names and values.
 	elem := tok.(*etree.Element)
 	for _, attr := range elem.Attr {
+		if attr.Key == "in_query" {
+			stmt.AddWarning(fmt.Errorf("'in_query' var is deprecated, use <in> directive instead in statement %+q", stmt.StmtName()))
+		}
 		info.values[attr.Key] = attr.Value
 	}
 
@@ -14,9 +14,10 @@ import (
 
 // WildcardsInfo contain wildcard information in a statement.
 type WildcardsInfo struct {
-	// len(wildcardColumns) == len(wildcardNames) == len(resultColumns)
-	wildcardColumns []*infos.ColumnInfo
-	wildcardNames   []string
+	// len(wildcardColumns) == len(wildcardNames) == len(wildcardDirectives) == len(resultColumns)
+	wildcardColumns    []*infos.ColumnInfo
+	wildcardNames      []string
+	wildcardDirectives []*wcDirective
 
 	loader          *datasrc.Loader
 	db              *infos.DBInfo
@@ -30,7 +31,10 @@ type wcDirective struct {
 	info       *WildcardsInfo
 	table      *infos.TableInfo
 	tableAlias string
-	idx        int // the idx-th wildcard directive in the statement
+	columns    []*infos.ColumnInfo // selected columns of the table
+	nullable   bool                // true if the whole wildcard can be NULL (e.g. the nullable side of an outer join)
+	partial    bool                // true if a partial struct of selected columns should be used instead of table struct
+	idx        int                 // the idx-th wildcard directive in the statement
 }
 
 var (
@@ -134,9 +138,9 @@ func (info *WildcardsInfo) processQueryResultColumns(resultCols *[]*datasrc.Colu
 				if curWildcard == nil {
 					return fmt.Errorf("<wc>: Expect in wildcard mode But not.")
 				}
-				if curWildcardColPos != curWildcard.table.NumColumn() {
+				if curWildcardColPos != len(curWildcard.columns) {
 					return fmt.Errorf("<wc>: Expect table column pos %d, but got %d.",
-						curWildcard.table.NumColumn(), curWildcardColPos)
+						len(curWildcard.columns), curWildcardColPos)
 				}
 				curWildcard = nil
 				curWildcardColPos = 0
@@ -154,11 +158,16 @@ func (info *WildcardsInfo) processQueryResultColumns(resultCols *[]*datasrc.Colu
 			// Not in wildcard mode
 			info.wildcardColumns = append(info.wildcardColumns, nil)
 			info.wildcardNames = append(info.wildcardNames, "")
+			info.wildcardDirectives = append(info.wildcardDirectives, nil)
 
 		} else {
 
 			// In wildcard mode
-			wildcardColumn := curWildcard.table.Column(curWildcardColPos)
+			if curWildcardColPos >= len(curWildcard.columns) {
+				return fmt.Errorf("<wc>: Invalid column pos %d for table %s.",
+					curWildcardColPos, curWildcard.table.String())
+			}
+			wildcardColumn := curWildcard.columns[curWildcardColPos]
 			if !wildcardColumn.Valid() {
 				return fmt.Errorf("<wc>: Invalid column pos %d for table %s.",
 					curWildcardColPos, curWildcard.table.String())
@@ -167,9 +176,14 @@ func (info *WildcardsInfo) processQueryResultColumns(resultCols *[]*datasrc.Colu
 				return fmt.Errorf("<wc>: Expect data type is %+q but got %+q for table column %s.%s",
 					wildcardColumn.Col().DataType, resultCol.DataType, wildcardColumn.Table().TableName(), wildcardColumn.ColumnName())
 			}
+			if curWildcard.nullable {
+				resultCol.HasNullable = true
+				resultCol.Nullable = true
+			}
 			curWildcardColPos += 1
 			info.wildcardColumns = append(info.wildcardColumns, wildcardColumn)
 			info.wildcardNames = append(info.wildcardNames, curWildcard.name())
+			info.wildcardDirectives = append(info.wildcardDirectives, curWildcard)
 
 		}
 
@@ -213,6 +227,72 @@ func (info *WildcardsInfo) WildcardName(i int) string {
 	return info.wildcardNames[i]
 }
 
+// WildcardFirst returns true if the i-th result column is the first column of a <wc> directive.
+func (info *WildcardsInfo) WildcardFirst(i int) bool {
+	if info == nil {
+		return false
+	}
+	if i < 0 || i >= len(info.wildcardDirectives) {
+		return false
+	}
+	d := info.wildcardDirectives[i]
+	return d != nil && (i == 0 || info.wildcardDirectives[i-1] != d)
+}
+
+// WildcardPartial returns true if the i-th result column is from a <wc partial="true"> directive,
+// in which case a partial struct containing only selected columns should be used instead of table struct.
+func (info *WildcardsInfo) WildcardPartial(i int) bool {
+	if info == nil {
+		return false
+	}
+	if i < 0 || i >= len(info.wildcardDirectives) {
+		return false
+	}
+	d := info.wildcardDirectives[i]
+	return d != nil && d.partial
+}
+
+// WildcardNullable returns true if the i-th result column is from a <wc nullable="true"> directive,
+// in which case the whole wildcard can be NULL.
+func (info *WildcardsInfo) WildcardNullable(i int) bool {
+	if info == nil {
+		return false
+	}
+	if i < 0 || i >= len(info.wildcardDirectives) {
+		return false
+	}
+	d := info.wildcardDirectives[i]
+	return d != nil && d.nullable
+}
+
+// ResultColIndex returns the index of the named result column of a statement. The name can be
+// either a result column name or "wildcardName.columnName" for a column from <wc>.
+func ResultColIndex(stmt *infos.StmtInfo, name string) (int, error) {
+
+	if name == "" {
+		return -1, fmt.Errorf("Missing result column name in statement %+q", stmt.StmtName())
+	}
+
+	wildcards := ExtractWildcardsInfo(stmt)
+	ret := -1
+	for i, resultCol := range stmt.ResultCols() {
+		if resultCol.Name != name &&
+			wildcards.WildcardName(i)+"."+wildcards.WildcardColumn(i).ColumnName() != name {
+			continue
+		}
+		if ret >= 0 {
+			return -1, fmt.Errorf("Ambiguous result column %+q in statement %+q", name, stmt.StmtName())
+		}
+		ret = i
+	}
+
+	if ret < 0 {
+		return -1, fmt.Errorf("Result column %+q not found in statement %+q", name, stmt.StmtName())
+	}
+	return ret, nil
+
+}
+
 func (d *wcDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
 
 	// Getset WildcardsInfo.
@@ -238,10 +318,29 @@ func (d *wcDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt
 	// Optinally alias
 	as := elem.SelectAttrValue("as", "")
 
+	// Optinally column subset
+	columns, err := selectColumns(table, elem.SelectAttrValue("columns", ""), elem.SelectAttrValue("exclude", ""))
+	if err != nil {
+		return err
+	}
+
+	// Optinally nullable/partial
+	nullable, err := boolAttr(elem, "nullable")
+	if err != nil {
+		return err
+	}
+	partial, err := boolAttr(elem, "partial")
+	if err != nil {
+		return err
+	}
+
 	// Set fields
 	d.info = info
 	d.table = table
 	d.tableAlias = as
+	d.columns = columns
+	d.nullable = nullable
+	d.partial = partial
 	d.idx = len(info.directives)
 
 	// Check wildcard name uniqueness
@@ -281,11 +380,11 @@ func (d *wcDirective) expansion() string {
 	prefix = loader.Quote(prefix)
 
 	fragments := []string{}
-	for i := 0; i < d.table.NumColumn(); i++ {
+	for i, column := range d.columns {
 		if i != 0 {
 			fragments = append(fragments, ", ")
 		}
-		columnName := loader.Quote(d.table.Column(i).ColumnName())
+		columnName := loader.Quote(column.ColumnName())
 		fragments = append(fragments, fmt.Sprintf("%s.%s", prefix, columnName))
 	}
 
@@ -300,6 +399,67 @@ func (d *wcDirective) name() string {
 	return d.table.TableName()
 }
 
+// selectColumns returns table columns selected by "columns" (in the given order) or "exclude" (in table order)
+// attributes. It returns all table columns if both are empty.
+func selectColumns(table *infos.TableInfo, columnsAttr, excludeAttr string) ([]*infos.ColumnInfo, error) {
+
+	if columnsAttr != "" && excludeAttr != "" {
+		return nil, fmt.Errorf("Only one of 'columns' or 'exclude' attribute can be specified in <wc> directive")
+	}
+
+	if columnsAttr != "" {
+		ret := []*infos.ColumnInfo{}
+		for _, columnName := range strings.Split(columnsAttr, ",") {
+			column := table.ColumnByName(strings.TrimSpace(columnName))
+			if column == nil {
+				return nil, fmt.Errorf("Column %+q not found in table %s", columnName, table.String())
+			}
+			for _, c := range ret {
+				if c == column {
+					return nil, fmt.Errorf("Duplicated column %+q in 'columns' attribute", columnName)
+				}
+			}
+			ret = append(ret, column)
+		}
+		return ret, nil
+	}
+
+	excluded := map[*infos.ColumnInfo]struct{}{}
+	if excludeAttr != "" {
+		for _, columnName := range strings.Split(excludeAttr, ",") {
+			column := table.ColumnByName(strings.TrimSpace(columnName))
+			if column == nil {
+				return nil, fmt.Errorf("Column %+q not found in table %s", columnName, table.String())
+			}
+			excluded[column] = struct{}{}
+		}
+	}
+
+	ret := []*infos.ColumnInfo{}
+	for _, column := range table.Columns() {
+		if _, found := excluded[column]; !found {
+			ret = append(ret, column)
+		}
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("No column selected in table %s", table.String())
+	}
+	return ret, nil
+
+}
+
+func boolAttr(elem *etree.Element, key string) (bool, error) {
+	attr := elem.SelectAttrValue(key, "")
+	if attr == "" {
+		return false, nil
+	}
+	v, err := strconv.ParseBool(attr)
+	if err != nil {
+		return false, fmt.Errorf("Invalid '%s' attribute %+q in <wc> directive", key, attr)
+	}
+	return v, nil
+}
+
 func init() {
 	infos.RegistDirectiveFactory(func() infos.Directive {
 		return &wcDirective{}
@@ -0,0 +1,129 @@
+package wcdir
+
+import (
+	"testing"
+
+	"github.com/huangjunwen/sqlw/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWildcardSubset(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := fakedb.NewFixture(t,
+		&fakedb.Table{
+			Name: "user",
+			Columns: []fakedb.Column{
+				{Name: "id", DataType: "int32"},
+				{Name: "name", DataType: "string"},
+				{Name: "password_hash", DataType: "string"},
+				{Name: "email", DataType: "string", Nullable: true},
+			},
+			Primary: []string{"id"},
+		},
+	)
+	defer fixture.Close()
+	user := fixture.DB.TableByName("user")
+
+	// Columns in the given order, partial struct.
+	{
+		stmt, err := fixture.Stmt(`<stmt name="A">SELECT <wc table="user" columns="email, id" partial="true" />, 1 AS one FROM user</stmt>`)
+		if assert.NoError(err) {
+			assert.Equal("SELECT `user`.`email`, `user`.`id`, 1 AS one FROM user", stmt.Text())
+			info := ExtractWildcardsInfo(stmt)
+			assert.Equal(user.ColumnByName("email"), info.WildcardColumn(0))
+			assert.Equal(user.ColumnByName("id"), info.WildcardColumn(1))
+			assert.Nil(info.WildcardColumn(2))
+			assert.True(info.WildcardFirst(0))
+			assert.False(info.WildcardFirst(1))
+			assert.True(info.WildcardPartial(0))
+			assert.True(info.WildcardPartial(1))
+			assert.False(info.WildcardPartial(2))
+		}
+	}
+
+	// Excluded columns, table struct with unselected fields left zero.
+	{
+		stmt, err := fixture.Stmt(`<stmt name="A">SELECT <wc table="user" as="u" exclude="password_hash" /> FROM user u</stmt>`)
+		if assert.NoError(err) {
+			assert.Equal("SELECT `u`.`id`, `u`.`name`, `u`.`email` FROM user u", stmt.Text())
+			info := ExtractWildcardsInfo(stmt)
+			assert.Equal("u", info.WildcardName(0))
+			assert.Equal(user.ColumnByName("email"), info.WildcardColumn(2))
+			assert.False(info.WildcardPartial(0))
+		}
+	}
+
+	for _, testCase := range []struct {
+		Stmt string
+		Err  string
+	}{
+		{
+			`<stmt name="A">SELECT <wc table="user" columns="id" exclude="email" /> FROM user</stmt>`,
+			`<wc>: Only one of 'columns' or 'exclude' attribute can be specified in <wc> directive`,
+		},
+		{
+			`<stmt name="A">SELECT <wc table="user" columns="id,nickname" /> FROM user</stmt>`,
+			`<wc>: Column "nickname" not found in table user`,
+		},
+		{
+			`<stmt name="A">SELECT <wc table="user" columns="id,id" /> FROM user</stmt>`,
+			`<wc>: Duplicated column "id" in 'columns' attribute`,
+		},
+		{
+			`<stmt name="A">SELECT <wc table="user" exclude="id,name,password_hash,email" /> FROM user</stmt>`,
+			`<wc>: No column selected in table user`,
+		},
+		{
+			`<stmt name="A">SELECT <wc table="user" columns="id" partial="yes" /> FROM user</stmt>`,
+			`<wc>: Invalid 'partial' attribute "yes" in <wc> directive`,
+		},
+	} {
+		_, err := fixture.Stmt(testCase.Stmt)
+		assert.EqualError(err, testCase.Err, "Stmt: %+q", testCase.Stmt)
+	}
+
+}
+
+func TestResultColIndex(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := fakedb.NewFixture(t,
+		&fakedb.Table{
+			Name:    "user",
+			Columns: []fakedb.Column{{Name: "id", DataType: "int32"}, {Name: "name", DataType: "string"}},
+			Primary: []string{"id"},
+		},
+	)
+	defer fixture.Close()
+
+	stmt, err := fixture.Stmt(`<stmt name="A">SELECT <wc table="user" as="u" />, u2.id, COUNT(*) AS n FROM user u JOIN user u2 ON u2.id=u.id</stmt>`)
+	if !assert.NoError(err) {
+		return
+	}
+
+	for _, testCase := range []struct {
+		Name  string
+		Index int
+		Err   string
+	}{
+		{"u.id", 0, ""},
+		{"u.name", 1, ""},
+		{"name", 1, ""},
+		{"n", 3, ""},
+		{"id", -1, `Ambiguous result column "id" in statement "A"`},
+		{"u2.name", -1, `Result column "u2.name" not found in statement "A"`},
+		{"", -1, `Missing result column name in statement "A"`},
+	} {
+		i, err := ResultColIndex(stmt, testCase.Name)
+		assert.Equal(testCase.Index, i, "Name: %+q", testCase.Name)
+		if testCase.Err == "" {
+			assert.NoError(err)
+		} else {
+			assert.EqualError(err, testCase.Err)
+		}
+	}
+
+}
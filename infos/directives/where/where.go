@@ -0,0 +1,38 @@
+package wheredir
+
+import (
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+// whereDirective is the directive for <where>. Leading "AND"/"OR" of its content is stripped and "WHERE" is
+// prepended if the content is not empty at runtime.
+type whereDirective struct {
+	elem  *etree.Element
+	begin string
+	end   string
+}
+
+var (
+	_ infos.NonterminalDirective = (*whereDirective)(nil)
+)
+
+func (d *whereDirective) Initialize(loader *datasrc.Loader, db *infos.DBInfo, stmt *infos.StmtInfo, tok etree.Token) error {
+	d.elem = tok.(*etree.Element)
+	d.begin, d.end = stmt.AddDynNode(infos.NewWhereNode())
+	return nil
+}
+
+func (d *whereDirective) Expand() ([]etree.Token, error) {
+	ret := []etree.Token{etree.NewCharData(d.begin)}
+	ret = append(ret, d.elem.Child...)
+	ret = append(ret, etree.NewCharData(d.end))
+	return ret, nil
+}
+
+func init() {
+	infos.RegistDirectiveFactory(func() infos.Directive {
+		return &whereDirective{}
+	}, "where")
+}
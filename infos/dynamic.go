@@ -0,0 +1,334 @@
+package infos
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Matches leading "AND"/"OR" in <where>.
+	dynWhereRe = regexp.MustCompile(`(?is)^\s*(?:AND|OR)\b`)
+)
+
+// DynNode is a node of a dynamic statement. Dynamic statements contain <if>/<where>/<set>/<foreach>/<in> directives
+// which are compiled into Go code branching on args instead of a single statement text.
+type DynNode struct {
+	kind       string // "text"/"if"/"where"/"set"/"foreach"/"in"
+	text       string // for "text"
+	test       string // for "if"
+	testIdents []string
+	collection string // for "foreach" and "in"
+	item       string
+	open       string
+	close      string
+	separator  string
+	expr       string // for "in"
+	empty      string
+	children   []*DynNode
+}
+
+const (
+	// If the number of <if> directives in a statement is no more than this, all combinations of branches are
+	// checked at generation time, otherwise only a representative set.
+	dynFullCombinationLimit = 4
+)
+
+// Kind returns the node kind: "text"/"if"/"where"/"set"/"foreach"/"in".
+func (node *DynNode) Kind() string {
+	return node.kind
+}
+
+// Text returns the positional text of a "text" node.
+func (node *DynNode) Text() string {
+	query, _ := compileNamedQuery(node.text)
+	return query
+}
+
+// ParamNames returns the named parameters of a "text" node, one for each placeholder in Text.
+func (node *DynNode) ParamNames() []string {
+	_, params := compileNamedQuery(node.text)
+	names := []string{}
+	for _, param := range params {
+		names = append(names, param.name)
+	}
+	return names
+}
+
+// Test returns the Go test expression of an "if" node.
+func (node *DynNode) Test() string {
+	return node.test
+}
+
+// Collection returns the slice arg name of a "foreach" or "in" node.
+func (node *DynNode) Collection() string {
+	return node.collection
+}
+
+// Item returns the item variable name of a "foreach" node.
+func (node *DynNode) Item() string {
+	return node.item
+}
+
+// Open returns the text before items of a "foreach" node.
+func (node *DynNode) Open() string {
+	return node.open
+}
+
+// Close returns the text after items of a "foreach" node.
+func (node *DynNode) Close() string {
+	return node.close
+}
+
+// Separator returns the text between items of a "foreach" node.
+func (node *DynNode) Separator() string {
+	return node.separator
+}
+
+// Expr returns the expression before "IN" of an "in" node. It's "" if only the parenthesized list is generated.
+func (node *DynNode) Expr() string {
+	return node.expr
+}
+
+// Empty returns the policy of an "in" node for empty slice: "error" to return an error, "false" to generate an always
+// false predicate or "skip" to generate an always true predicate.
+func (node *DynNode) Empty() string {
+	return node.empty
+}
+
+// Children returns child nodes.
+func (node *DynNode) Children() []*DynNode {
+	return node.children
+}
+
+// NewIfNode creates an "if" node. test is a Go expression on args, the branch is taken if it is true.
+func NewIfNode(test string) (*DynNode, error) {
+	node := &DynNode{
+		kind: "if",
+		test: strings.TrimSpace(test),
+	}
+	expr, err := parser.ParseExpr(node.test)
+	if err != nil {
+		return nil, err
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			node.testIdents = append(node.testIdents, ident.Name)
+		}
+		return true
+	})
+	return node, nil
+}
+
+// NewWhereNode creates a "where" node. Leading "AND"/"OR" of its content is stripped and "WHERE" is prepended if
+// the content is not empty.
+func NewWhereNode() *DynNode {
+	return &DynNode{
+		kind: "where",
+	}
+}
+
+// NewSetNode creates a "set" node. Trailing "," of its content is stripped and "SET" is prepended if the content is
+// not empty.
+func NewSetNode() *DynNode {
+	return &DynNode{
+		kind: "set",
+	}
+}
+
+// NewForeachNode creates a "foreach" node. Its content is repeated for each item of the collection arg.
+func NewForeachNode(collection, item, open, close, separator string) *DynNode {
+	return &DynNode{
+		kind:       "foreach",
+		collection: collection,
+		item:       item,
+		open:       open,
+		close:      close,
+		separator:  separator,
+	}
+}
+
+// NewInNode creates an "in" node. It expands the collection arg to an IN-list, expr is the expression before "IN" or ""
+// if only the parenthesized list is generated. empty is the policy for empty slice, see DynNode.Empty.
+func NewInNode(collection, expr, empty string) *DynNode {
+	return &DynNode{
+		kind:       "in",
+		collection: collection,
+		expr:       expr,
+		empty:      empty,
+	}
+}
+
+// AddDynNode registers a dynamic node in the statement, which makes the statement dynamic. It returns the begin and end
+// markers which must surround the node's content in query fragments and final fragments of directives.
+func (info *StmtInfo) AddDynNode(node *DynNode) (begin, end string) {
+	id := len(info.dynNodes)
+	info.dynNodes = append(info.dynNodes, node)
+	return dynMarker(id, true), dynMarker(id, false)
+}
+
+func dynMarker(id int, isBegin bool) string {
+	if isBegin {
+		return "\x00dyn" + strconv.Itoa(id) + "b\x00"
+	}
+	return "\x00dyn" + strconv.Itoa(id) + "e\x00"
+}
+
+// parseDyn parses a text with dynamic markers into DynNode tree. nodes are the registered nodes indexed by id.
+func parseDyn(s string, nodes []*DynNode) ([]*DynNode, error) {
+
+	type frame struct {
+		id   int
+		node *DynNode
+	}
+
+	root := &DynNode{}
+	stack := []frame{{-1, root}}
+
+	addText := func(text string) {
+		if text == "" {
+			return
+		}
+		parent := stack[len(stack)-1].node
+		parent.children = append(parent.children, &DynNode{
+			kind: "text",
+			text: text,
+		})
+	}
+
+	for {
+		i := strings.Index(s, "\x00dyn")
+		if i < 0 {
+			addText(s)
+			break
+		}
+		addText(s[:i])
+		s = s[i+len("\x00dyn"):]
+
+		j := strings.IndexByte(s, '\x00')
+		if j < 1 {
+			return nil, fmt.Errorf("Invalid dynamic marker")
+		}
+		id, err := strconv.Atoi(s[:j-1])
+		if err != nil || id < 0 || id >= len(nodes) {
+			return nil, fmt.Errorf("Invalid dynamic marker")
+		}
+		isBegin := s[j-1] == 'b'
+		s = s[j+1:]
+
+		if isBegin {
+			node := &DynNode{}
+			*node = *nodes[id]
+			node.children = nil
+			parent := stack[len(stack)-1].node
+			parent.children = append(parent.children, node)
+			stack = append(stack, frame{id, node})
+			continue
+		}
+
+		if stack[len(stack)-1].id != id {
+			return nil, fmt.Errorf("Unbalanced dynamic marker")
+		}
+		stack = stack[:len(stack)-1]
+	}
+
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("Unbalanced dynamic marker")
+	}
+	return root.children, nil
+
+}
+
+// dynWhere strips leading "AND"/"OR" and prepends "WHERE" if not empty. It's used by <where>.
+//
+// NOTE: Generated code has the same helper (see helper.tmpl), keep them in sync so that the checked statement
+// variants are the same as those built at runtime.
+func dynWhere(s string) string {
+	s = strings.TrimSpace(dynWhereRe.ReplaceAllString(s, ""))
+	if s == "" {
+		return " "
+	}
+	return " WHERE " + s + " "
+}
+
+// dynSet strips trailing "," and prepends "SET" if not empty. It's used by <set>.
+func dynSet(s string) string {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), ","))
+	if s == "" {
+		return " "
+	}
+	return " SET " + s + " "
+}
+
+// renderDyn renders a concrete text from DynNode tree. on contains "if" nodes whose branch is taken.
+// "foreach" nodes are rendered with a single item.
+func renderDyn(nodes []*DynNode, on map[*DynNode]bool) string {
+	fragments := []string{}
+	for _, node := range nodes {
+		switch node.kind {
+		case "text":
+			fragments = append(fragments, node.text)
+		case "if":
+			if on[node] {
+				fragments = append(fragments, renderDyn(node.children, on))
+			}
+		case "where":
+			fragments = append(fragments, dynWhere(renderDyn(node.children, on)))
+		case "set":
+			fragments = append(fragments, dynSet(renderDyn(node.children, on)))
+		case "foreach":
+			fragments = append(fragments, node.open, renderDyn(node.children, on), node.close)
+		case "in":
+			fragments = append(fragments, renderDyn(node.children, on))
+		}
+	}
+	return strings.Join(fragments, "")
+}
+
+// dynVariants returns concrete texts for branch combinations of DynNode tree. The first one takes all branches.
+func dynVariants(nodes []*DynNode) []string {
+
+	ifNodes := []*DynNode{}
+	var collect func([]*DynNode)
+	collect = func(nodes []*DynNode) {
+		for _, node := range nodes {
+			if node.kind == "if" {
+				ifNodes = append(ifNodes, node)
+			}
+			collect(node.children)
+		}
+	}
+	collect(nodes)
+
+	choices := []map[*DynNode]bool{}
+	if len(ifNodes) <= dynFullCombinationLimit {
+		// All combinations.
+		for mask := (1 << uint(len(ifNodes))) - 1; mask >= 0; mask-- {
+			on := map[*DynNode]bool{}
+			for i, node := range ifNodes {
+				on[node] = mask&(1<<uint(i)) != 0
+			}
+			choices = append(choices, on)
+		}
+	} else {
+		// All branches, no branch and each single branch.
+		all := map[*DynNode]bool{}
+		for _, node := range ifNodes {
+			all[node] = true
+		}
+		choices = append(choices, all, map[*DynNode]bool{})
+		for _, node := range ifNodes {
+			choices = append(choices, map[*DynNode]bool{node: true})
+		}
+	}
+
+	ret := []string{}
+	for _, on := range choices {
+		ret = append(ret, strings.TrimSpace(renderDyn(nodes, on)))
+	}
+	return ret
+
+}
@@ -0,0 +1,135 @@
+package infos
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynVariants(t *testing.T) {
+
+	assert := assert.New(t)
+
+	mustIf := func(test string) *DynNode {
+		node, err := NewIfNode(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	// <where>, <if> and <foreach>.
+	{
+		stmt := &StmtInfo{}
+		whereBegin, whereEnd := stmt.AddDynNode(NewWhereNode())
+		if1Begin, if1End := stmt.AddDynNode(mustIf("name != nil"))
+		if2Begin, if2End := stmt.AddDynNode(mustIf("len(ids) != 0"))
+		foreachBegin, foreachEnd := stmt.AddDynNode(NewForeachNode("ids", "id", "(", ")", ", "))
+
+		tree, err := parseDyn("SELECT * FROM user"+
+			whereBegin+
+			if1Begin+" AND name=:name"+if1End+
+			if2Begin+" AND id IN "+foreachBegin+":id"+foreachEnd+if2End+
+			whereEnd+
+			" ORDER BY id", stmt.dynNodes)
+		assert.NoError(err)
+
+		kinds := []string{}
+		for _, node := range tree {
+			kinds = append(kinds, node.Kind())
+		}
+		assert.Equal([]string{"text", "where", "text"}, kinds)
+		assert.Len(tree[1].Children(), 2)
+		assert.Equal([]string{"name", "nil"}, tree[1].Children()[0].testIdents)
+		assert.Equal([]string{"len", "ids"}, tree[1].Children()[1].testIdents)
+
+		assert.Equal([]string{
+			"SELECT * FROM user WHERE name=:name AND id IN (:id)  ORDER BY id",
+			"SELECT * FROM user WHERE id IN (:id)  ORDER BY id",
+			"SELECT * FROM user WHERE name=:name  ORDER BY id",
+			"SELECT * FROM user  ORDER BY id",
+		}, dynVariants(tree))
+	}
+
+	// <set>
+	{
+		stmt := &StmtInfo{}
+		setBegin, setEnd := stmt.AddDynNode(NewSetNode())
+		if1Begin, if1End := stmt.AddDynNode(mustIf("name != nil"))
+		if2Begin, if2End := stmt.AddDynNode(mustIf("email != nil"))
+
+		tree, err := parseDyn("UPDATE user"+
+			setBegin+
+			if1Begin+" name=:name,"+if1End+
+			if2Begin+" email=:email,"+if2End+
+			setEnd+
+			"WHERE id=:id", stmt.dynNodes)
+		assert.NoError(err)
+		assert.Equal([]string{
+			"UPDATE user SET name=:name, email=:email WHERE id=:id",
+			"UPDATE user SET email=:email WHERE id=:id",
+			"UPDATE user SET name=:name WHERE id=:id",
+			"UPDATE user WHERE id=:id",
+		}, dynVariants(tree))
+	}
+
+	// Unbalanced markers.
+	{
+		stmt := &StmtInfo{}
+		begin, _ := stmt.AddDynNode(NewWhereNode())
+		_, err := parseDyn("SELECT 1"+begin, stmt.dynNodes)
+		assert.Error(err)
+	}
+
+	// Invalid test.
+	{
+		_, err := NewIfNode("name !=")
+		assert.Error(err)
+	}
+
+}
+
+func TestDynVariantsRepresentative(t *testing.T) {
+
+	assert := assert.New(t)
+
+	// With more than dynFullCombinationLimit <if>s, only all branches, no branch and each single branch are checked.
+	stmt := &StmtInfo{}
+	whereBegin, whereEnd := stmt.AddDynNode(NewWhereNode())
+	query := "SELECT * FROM t" + whereBegin
+	n := dynFullCombinationLimit + 1
+	for i := 0; i < n; i++ {
+		node, err := NewIfNode(fmt.Sprintf("a%d != nil", i))
+		assert.NoError(err)
+		begin, end := stmt.AddDynNode(node)
+		query += begin + fmt.Sprintf(" AND a%d=:a%d", i, i) + end
+	}
+	query += whereEnd
+
+	tree, err := parseDyn(query, stmt.dynNodes)
+	assert.NoError(err)
+
+	variants := dynVariants(tree)
+	assert.Len(variants, n+2)
+	assert.Equal("SELECT * FROM t WHERE a0=:a0 AND a1=:a1 AND a2=:a2 AND a3=:a3 AND a4=:a4", variants[0])
+	assert.Equal("SELECT * FROM t", variants[1])
+	for i := 0; i < n; i++ {
+		assert.Equal(fmt.Sprintf("SELECT * FROM t WHERE a%d=:a%d", i, i), variants[i+2])
+	}
+
+}
+
+func TestDynWhereSet(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.Equal(" WHERE a=1 AND b=2 ", dynWhere(" AND a=1 AND b=2"))
+	assert.Equal(" WHERE a=1 ", dynWhere("\n or a=1"))
+	assert.Equal(" WHERE ORDER_NO=1 ", dynWhere("ORDER_NO=1"))
+	assert.Equal(" ", dynWhere("  "))
+
+	assert.Equal(" SET a=1, b=2 ", dynSet("a=1, b=2, "))
+	assert.Equal(" ", dynSet(" , "))
+
+}
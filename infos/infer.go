@@ -0,0 +1,194 @@
+package infos
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Matches a column reference followed by a comparison operator at the end of text: "`t`.`col` = "
+	colRefBeforeRe = regexp.MustCompile("(?:(`[^`]+`|\\w+)\\s*\\.\\s*)?(`[^`]+`|\\w+)\\s*(?:<=>|<>|!=|<=|>=|=|<|>)\\s*$")
+
+	// Matches a comparison operator followed by a column reference at the beginning of text: " = `t`.`col`"
+	colRefAfterRe = regexp.MustCompile("^\\s*(?:<=>|<>|!=|<=|>=|=|<|>)\\s*(?:(`[^`]+`|\\w+)\\s*\\.\\s*)?(`[^`]+`|\\w+)")
+
+	// Words can't be a table alias.
+	nonAliasWords = map[string]struct{}{
+		"WHERE": {}, "SET": {}, "ON": {}, "USING": {}, "JOIN": {}, "LEFT": {}, "RIGHT": {}, "INNER": {},
+		"OUTER": {}, "CROSS": {}, "NATURAL": {}, "STRAIGHT_JOIN": {}, "GROUP": {}, "ORDER": {}, "LIMIT": {},
+		"HAVING": {}, "VALUES": {}, "VALUE": {}, "SELECT": {}, "FOR": {}, "LOCK": {}, "UNION": {},
+		"PARTITION": {}, "USE": {}, "FORCE": {}, "IGNORE": {}, "WINDOW": {},
+	}
+)
+
+func unquoteIdent(s string) string {
+	return strings.Trim(s, "`")
+}
+
+// tableRef is a table reference in a query.
+type tableRef struct {
+	keyword   string // upper cased keyword before the reference: "FROM"/"JOIN"/"UPDATE"/"INTO"
+	outerJoin string // "LEFT"/"RIGHT" for outer joins, "" otherwise
+	depth     int    // parenthesis depth of the reference, e.g. 1 for tables in a subquery of the main statement
+	tableName string
+	alias     string // "" if no alias
+}
+
+// findTableRefs returns table references in the query in order of appearance.
+func findTableRefs(query string) []tableRef {
+
+	tokens := significantTokens(query)
+	depths := tokenDepths(tokens)
+	ret := []tableRef{}
+
+	for i := 0; i < len(tokens); i++ {
+
+		keyword := tokens[i].keyword()
+		switch keyword {
+		case "FROM", "JOIN", "UPDATE", "INTO":
+		default:
+			continue
+		}
+
+		outerJoin := ""
+		if keyword == "JOIN" {
+			j := i - 1
+			if j >= 0 && tokens[j].keyword() == "OUTER" {
+				j--
+			}
+			if j >= 0 && (tokens[j].keyword() == "LEFT" || tokens[j].keyword() == "RIGHT") {
+				outerJoin = tokens[j].keyword()
+			}
+		}
+
+		for {
+			ref, n := parseTableRef(tokens[i+1:])
+			if n == 0 {
+				break
+			}
+			ref.keyword = keyword
+			ref.outerJoin = outerJoin
+			ref.depth = depths[i]
+			ret = append(ret, ref)
+			i += n
+
+			// Comma separated table references: "FROM a, b" or "UPDATE a, b".
+			if (keyword != "FROM" && keyword != "UPDATE") || i+1 >= len(tokens) || tokens[i+1].text != "," {
+				break
+			}
+			i++
+		}
+	}
+
+	return ret
+
+}
+
+// parseTableRef parses a table reference at the beginning of tokens: "tbl", "db.tbl", "tbl AS t" or "tbl t".
+// It returns the reference and the number of tokens consumed, which is 0 if tokens don't start with a table name.
+func parseTableRef(tokens []sqlToken) (tableRef, int) {
+
+	ref := tableRef{}
+	if len(tokens) == 0 || !tokens[0].isIdent() {
+		return ref, 0
+	}
+	ref.tableName = tokens[0].ident()
+	n := 1
+
+	// Qualified by database name.
+	if len(tokens) >= 3 && tokens[1].text == "." && tokens[2].isIdent() {
+		ref.tableName = tokens[2].ident()
+		n = 3
+	}
+
+	if len(tokens) > n+1 && tokens[n].keyword() == "AS" && tokens[n+1].isIdent() {
+		ref.alias = tokens[n+1].ident()
+		return ref, n + 2
+	}
+	if len(tokens) > n && tokens[n].isIdent() {
+		if _, found := nonAliasWords[tokens[n].keyword()]; !found {
+			ref.alias = tokens[n].ident()
+			return ref, n + 1
+		}
+	}
+	return ref, n
+
+}
+
+// tableRefs returns tables referenced in the query, keyed by both table names and aliases.
+func tableRefs(db *DBInfo, query string) map[string]*TableInfo {
+	return refTables(db, findTableRefs(query))
+}
+
+// refTables returns tables of the table references, keyed by both table names and aliases.
+func refTables(db *DBInfo, refs []tableRef) map[string]*TableInfo {
+	ret := map[string]*TableInfo{}
+	for _, ref := range refs {
+		table := db.TableByName(ref.tableName)
+		if table == nil {
+			continue
+		}
+		ret[table.TableName()] = table
+		if ref.alias != "" {
+			ret[ref.alias] = table
+		}
+	}
+	return ret
+}
+
+// ColumnComparedWith returns the table column directly compared with the named parameter in the statement text,
+// e.g. "user_id" in "WHERE user_id=:userId". It is used to infer arg type. It returns nil if not found or ambiguous.
+//
+// NOTE: This is a heuristic based on the statement text, only simple comparisons ("=", "<>", "<" ...) with column
+// references (optionally qualified by table name or alias) are recognized.
+func (info *StmtInfo) ColumnComparedWith(paramName string) *ColumnInfo {
+	if info == nil {
+		return nil
+	}
+
+	tables := tableRefs(info.db, info.text)
+	_, params := compileNamedQuery(info.text)
+
+	for _, param := range params {
+		if param.name != paramName {
+			continue
+		}
+
+		m := colRefBeforeRe.FindStringSubmatch(info.text[:param.offset])
+		if m == nil {
+			m = colRefAfterRe.FindStringSubmatch(info.text[param.offset+1+len(param.name):])
+		}
+		if m == nil {
+			continue
+		}
+
+		if column := resolveColumnRef(tables, unquoteIdent(m[1]), unquoteIdent(m[2])); column != nil {
+			return column
+		}
+	}
+
+	return nil
+}
+
+func resolveColumnRef(tables map[string]*TableInfo, tableRef, columnName string) *ColumnInfo {
+
+	// Qualified.
+	if tableRef != "" {
+		return tables[tableRef].ColumnByName(columnName)
+	}
+
+	// Unqualified, must be unique among referenced tables.
+	ret := (*ColumnInfo)(nil)
+	for _, table := range tables {
+		column := table.ColumnByName(columnName)
+		if column == nil || column == ret {
+			continue
+		}
+		if ret != nil {
+			return nil
+		}
+		ret = column
+	}
+	return ret
+
+}
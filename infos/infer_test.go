@@ -0,0 +1,72 @@
+package infos_test
+
+import (
+	"testing"
+
+	"github.com/huangjunwen/sqlw/infos/directives/arg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgTypeInference(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := newTestFixture(t)
+	defer fixture.Close()
+
+	user, blog := fixture.DB.TableByName("user"), fixture.DB.TableByName("blog")
+
+	stmt, err := fixture.Stmt(`<stmt name="BlogsOfUser">
+  <arg name="userId" />
+  <arg name="title" />
+  <arg name="minId" type="@blog.id" />
+  <arg name="email" column="user.email" />
+  <arg name="limit" type="int" />
+  SELECT b.id FROM blog b JOIN user u ON u.id=b.user_id
+  WHERE b.user_id = :userId AND :title&lt;&gt;` + "`b`.`title`" + ` AND b.id > :minId AND u.email=:email LIMIT :limit
+</stmt>`)
+	if assert.NoError(err) {
+		args := argdir.ExtractArgsInfo(stmt).Args()
+		if assert.Len(args, 5) {
+			assert.Equal(blog.ColumnByName("user_id"), args[0].ArgColumn())
+			assert.Equal(blog.ColumnByName("title"), args[1].ArgColumn())
+			assert.Equal(blog.ColumnByName("id"), args[2].ArgColumn())
+			assert.Equal(user.ColumnByName("email"), args[3].ArgColumn())
+			assert.Equal("", args[3].ArgType())
+			assert.Nil(args[4].ArgColumn())
+			assert.Equal("int", args[4].ArgType())
+		}
+	}
+
+	for _, testCase := range []struct {
+		Stmt string
+		Err  string
+	}{
+		// Ambiguous unqualified column.
+		{
+			`<stmt name="A"><arg name="id" />SELECT 1 AS one FROM blog JOIN user ON user.id=blog.user_id WHERE id=:id</stmt>`,
+			`<arg>: Can't infer type of arg "id" in statement "A", please specify 'type' or 'column' attribute in <arg> directive`,
+		},
+		// Not compared with a column.
+		{
+			`<stmt name="A"><arg name="n" />SELECT 1 AS one FROM user LIMIT :n</stmt>`,
+			`<arg>: Can't infer type of arg "n" in statement "A", please specify 'type' or 'column' attribute in <arg> directive`,
+		},
+		{
+			`<stmt name="A"><arg name="id" type="@user" />SELECT 1 AS one FROM user WHERE id=:id</stmt>`,
+			`<arg>: Expect column reference in the form of "table.column" but got "user"`,
+		},
+		{
+			`<stmt name="A"><arg name="id" column="user.uid" />SELECT 1 AS one FROM user WHERE id=:id</stmt>`,
+			`<arg>: Column "uid" not found in table "user"`,
+		},
+		{
+			`<stmt name="A"><arg name="id" type="int" column="user.id" />SELECT 1 AS one FROM user WHERE id=:id</stmt>`,
+			`<arg>: Only one of 'type' or 'column' attribute can be specified in <arg> directive`,
+		},
+	} {
+		_, err := fixture.Stmt(testCase.Stmt)
+		assert.EqualError(err, testCase.Err, "Stmt: %+q", testCase.Stmt)
+	}
+
+}
@@ -0,0 +1,308 @@
+package infos
+
+import (
+	"fmt"
+	"strings"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlWord    sqlTokenKind = iota // keyword, identifier or number
+	sqlQuoted                      // `quoted identifier`
+	sqlString                      // 'string' or "string"
+	sqlComment                     // "-- ...", "# ..." or "/* ... */"
+	sqlSpace
+	sqlPunct // any other single byte
+)
+
+// sqlToken is a lexical token of MySQL statement.
+type sqlToken struct {
+	kind   sqlTokenKind
+	text   string
+	offset int
+}
+
+// stmtClass is the classification of a statement.
+type stmtClass struct {
+	verb             string // main verb, "SELECT"/"INSERT"/"UPDATE"/"DELETE"/"REPLACE"/"CALL"
+	hasLocking       bool   // "FOR UPDATE"/"FOR SHARE"/"LOCK IN SHARE MODE"
+	isMultiStatement bool   // more than one statement separated by ';'
+}
+
+// lexSQL splits a MySQL statement into tokens. It never fails: unterminated strings or comments extend to the end.
+func lexSQL(s string) []sqlToken {
+
+	tokens := []sqlToken{}
+	for i := 0; i < len(s); {
+		start := i
+		kind := sqlPunct
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			kind = sqlSpace
+			for i < len(s) && strings.IndexByte(" \t\n\r\f\v", s[i]) >= 0 {
+				i++
+			}
+
+		case c == '#' || (c == '-' && strings.HasPrefix(s[i:], "--") && (i+2 >= len(s) || strings.IndexByte(" \t\n\r", s[i+2]) >= 0)):
+			// NOTE: MySQL requires a whitespace after "--".
+			kind = sqlComment
+			if j := strings.IndexByte(s[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(s)
+			}
+
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			kind = sqlComment
+			if j := strings.Index(s[i+2:], "*/"); j >= 0 {
+				i += 2 + j + 2
+			} else {
+				i = len(s)
+			}
+
+		case c == '\'' || c == '"' || c == '`':
+			kind = sqlString
+			if c == '`' {
+				kind = sqlQuoted
+			}
+			i++
+			for i < len(s) {
+				if s[i] == '\\' && c != '`' {
+					i += 2
+					continue
+				}
+				if s[i] == c {
+					// Doubled quote is an escaped quote.
+					if i+1 < len(s) && s[i+1] == c {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+			if i > len(s) {
+				i = len(s)
+			}
+
+		case isWordByte(c):
+			kind = sqlWord
+			for i < len(s) && isWordByte(s[i]) {
+				i++
+			}
+
+		default:
+			i++
+		}
+
+		tokens = append(tokens, sqlToken{
+			kind:   kind,
+			text:   s[start:i],
+			offset: start,
+		})
+	}
+	return tokens
+
+}
+
+// significantTokens returns tokens of a MySQL statement except spaces and comments.
+func significantTokens(s string) []sqlToken {
+	ret := []sqlToken{}
+	for _, token := range lexSQL(s) {
+		if token.kind != sqlSpace && token.kind != sqlComment {
+			ret = append(ret, token)
+		}
+	}
+	return ret
+}
+
+// tokenDepths returns parenthesis depth of each token. Parentheses themselves have the depth outside them.
+func tokenDepths(tokens []sqlToken) []int {
+	ret := make([]int, len(tokens))
+	depth := 0
+	for i, token := range tokens {
+		if token.kind == sqlPunct && token.text == ")" {
+			depth--
+		}
+		ret[i] = depth
+		if token.kind == sqlPunct && token.text == "(" {
+			depth++
+		}
+	}
+	return ret
+}
+
+// keyword returns the upper cased text of a word token, or "" if it is not a word.
+func (token sqlToken) keyword() string {
+	if token.kind != sqlWord {
+		return ""
+	}
+	return strings.ToUpper(token.text)
+}
+
+// isIdent returns true if the token can be an identifier: a quoted identifier or a word not starting with digit.
+func (token sqlToken) isIdent() bool {
+	switch token.kind {
+	case sqlQuoted:
+		return true
+	case sqlWord:
+		return token.text[0] < '0' || token.text[0] > '9'
+	}
+	return false
+}
+
+// ident returns the identifier name of the token: quotes of quoted identifier are removed.
+func (token sqlToken) ident() string {
+	if token.kind != sqlQuoted {
+		return token.text
+	}
+	return strings.Replace(strings.TrimSuffix(token.text[1:], "`"), "``", "`", -1)
+}
+
+func isWordByte(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '$' || c >= 0x80
+}
+
+// classifyStmt determines the main verb of a statement, skipping leading comments and parentheses.
+// For CTE ("WITH ... AS (...) SELECT ..."), the verb is the one following the CTE definitions.
+func classifyStmt(query string) (*stmtClass, error) {
+
+	tokens := significantTokens(query)
+
+	class := &stmtClass{}
+	depth := 0
+	verbDepth := -1
+	inCTE := false
+
+	for i, token := range tokens {
+
+		if token.kind == sqlPunct {
+			switch token.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			case ";":
+				if i != len(tokens)-1 {
+					class.isMultiStatement = true
+				}
+			}
+			continue
+		}
+		if token.kind != sqlWord {
+			continue
+		}
+
+		word := strings.ToUpper(token.text)
+
+		// Looking for the verb.
+		if class.verb == "" {
+			if inCTE {
+				// Skip CTE definitions, which are inside parentheses or separated by ','.
+				if depth != 0 {
+					continue
+				}
+				switch word {
+				case "SELECT", "INSERT", "UPDATE", "DELETE", "REPLACE":
+				default:
+					continue
+				}
+			}
+
+			switch word {
+			case "WITH":
+				inCTE = true
+				continue
+			case "SELECT", "INSERT", "UPDATE", "DELETE", "REPLACE", "CALL":
+				class.verb = word
+				verbDepth = depth
+				continue
+			default:
+				return nil, fmt.Errorf("Not supported statement type %+q", token.text)
+			}
+		}
+
+		// Locking clauses of the main statement.
+		if depth != verbDepth || i+1 >= len(tokens) {
+			continue
+		}
+		next := strings.ToUpper(tokens[i+1].text)
+		switch {
+		case word == "FOR" && (next == "UPDATE" || next == "SHARE"):
+			class.hasLocking = true
+		case word == "LOCK" && next == "IN":
+			class.hasLocking = true
+		}
+	}
+
+	if class.verb == "" {
+		return nil, fmt.Errorf("Can't determine statement type for %+q", query)
+	}
+	return class, nil
+
+}
+
+// limitZeroQuery rewrites a SELECT statement to return no rows so that its result columns can be loaded without
+// scanning any table: the trailing ';', the top level LIMIT clause and locking clauses are removed, then "LIMIT 0" is
+// appended. Locking clauses are removed since they would lock rows.
+func limitZeroQuery(query string) string {
+
+	tokens := significantTokens(query)
+
+	buf := strings.Builder{}
+	end := len(query) // end of the main statement
+	prev := 0         // copied up to
+	depth := 0
+	skipDepth := -1 // skipping a locking clause at this depth, -1 if not skipping
+
+	for i, token := range tokens {
+
+		if token.kind == sqlPunct {
+			switch token.text {
+			case "(":
+				depth++
+			case ")":
+				if depth == skipDepth {
+					// End of the parenthesized statement with a locking clause.
+					prev = token.offset
+					skipDepth = -1
+				}
+				depth--
+			case ";":
+				if depth == 0 && end == len(query) {
+					end = token.offset
+				}
+			}
+			continue
+		}
+		if token.kind != sqlWord || skipDepth >= 0 || end != len(query) {
+			continue
+		}
+
+		word := strings.ToUpper(token.text)
+		next := ""
+		if i+1 < len(tokens) {
+			next = strings.ToUpper(tokens[i+1].text)
+		}
+		switch {
+		case word == "FOR" && (next == "UPDATE" || next == "SHARE"), word == "LOCK" && next == "IN":
+			buf.WriteString(query[prev:token.offset])
+			skipDepth = depth
+		case word == "LIMIT" && depth == 0:
+			buf.WriteString(query[prev:token.offset])
+			skipDepth = depth
+		}
+	}
+
+	if skipDepth < 0 && prev < end {
+		buf.WriteString(query[prev:end])
+	}
+
+	// NOTE: "\n" is needed in case that query ends with a "-- comment".
+	return strings.TrimSpace(buf.String()) + "\nLIMIT 0"
+
+}
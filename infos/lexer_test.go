@@ -0,0 +1,79 @@
+package infos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyStmt(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, testCase := range []struct {
+		Query            string
+		Verb             string
+		HasLocking       bool
+		IsMultiStatement bool
+	}{
+		{"SELECT 1", "SELECT", false, false},
+		{"select\n1", "SELECT", false, false},
+		{"-- comment\n/* comment */ # comment\nUPDATE t SET a=1", "UPDATE", false, false},
+		{"(SELECT 1) UNION (SELECT 2)", "SELECT", false, false},
+		{"WITH cte AS (SELECT 1 AS a), c2 (b) AS (SELECT 2) SELECT * FROM cte, c2", "SELECT", false, false},
+		{"WITH RECURSIVE cte AS (SELECT 1 UNION ALL SELECT n+1 FROM cte) DELETE FROM t WHERE id IN (SELECT * FROM cte)", "DELETE", false, false},
+		{"CALL proc(1)", "CALL", false, false},
+		{"REPLACE INTO t VALUES (1)", "REPLACE", false, false},
+		{"SELECT * FROM t WHERE a='FOR UPDATE' FOR UPDATE", "SELECT", true, false},
+		{"SELECT * FROM t FOR SHARE NOWAIT", "SELECT", true, false},
+		{"SELECT * FROM t LOCK IN SHARE MODE", "SELECT", true, false},
+		{"SELECT * FROM t WHERE id IN (SELECT id FROM t2 FOR UPDATE)", "SELECT", false, false},
+		{"SELECT `for` FROM t", "SELECT", false, false},
+		{"SELECT 1;", "SELECT", false, false},
+		{"SELECT ';'; -- end", "SELECT", false, false},
+		{"SELECT 1; SELECT 2", "SELECT", false, true},
+		{"SELECT 'it''s \\' -- not comment'; DELETE FROM t", "SELECT", false, true},
+	} {
+		class, err := classifyStmt(testCase.Query)
+		if !assert.NoError(err, "Query: %+q", testCase.Query) {
+			continue
+		}
+		assert.Equal(testCase.Verb, class.verb, "Query: %+q", testCase.Query)
+		assert.Equal(testCase.HasLocking, class.hasLocking, "Query: %+q", testCase.Query)
+		assert.Equal(testCase.IsMultiStatement, class.isMultiStatement, "Query: %+q", testCase.Query)
+	}
+
+	for _, query := range []string{
+		"",
+		"-- only comment",
+		"SHOW TABLES",
+		"WITH cte AS (SELECT 1)",
+	} {
+		_, err := classifyStmt(query)
+		assert.Error(err, "Query: %+q", query)
+	}
+
+}
+
+func TestLimitZeroQuery(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, testCase := range []struct {
+		Query    string
+		Expected string
+	}{
+		{"SELECT 1", "SELECT 1\nLIMIT 0"},
+		{"SELECT 1;", "SELECT 1\nLIMIT 0"},
+		{"SELECT ';' -- end\n; -- end", "SELECT ';' -- end\nLIMIT 0"},
+		{"SELECT * FROM t ORDER BY id LIMIT 10, 20", "SELECT * FROM t ORDER BY id\nLIMIT 0"},
+		{"SELECT * FROM t LIMIT :limit FOR UPDATE;", "SELECT * FROM t\nLIMIT 0"},
+		{"SELECT * FROM t LOCK IN SHARE MODE", "SELECT * FROM t\nLIMIT 0"},
+		{"SELECT * FROM t WHERE id IN (SELECT id FROM t2 LIMIT 1 FOR UPDATE) ORDER BY id", "SELECT * FROM t WHERE id IN (SELECT id FROM t2 LIMIT 1 ) ORDER BY id\nLIMIT 0"},
+		{"(SELECT a FROM t LIMIT 1) UNION (SELECT b FROM t2) LIMIT 5", "(SELECT a FROM t LIMIT 1) UNION (SELECT b FROM t2)\nLIMIT 0"},
+		{"SELECT a FROM t WHERE b='LIMIT 1' -- LIMIT 2", "SELECT a FROM t WHERE b='LIMIT 1' -- LIMIT 2\nLIMIT 0"},
+	} {
+		assert.Equal(testCase.Expected, limitZeroQuery(testCase.Query), "Query: %+q", testCase.Query)
+	}
+
+}
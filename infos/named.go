@@ -0,0 +1,103 @@
+package infos
+
+import (
+	"strings"
+)
+
+// namedParam is a named parameter in a named query.
+type namedParam struct {
+	name   string
+	offset int // byte offset of the leading ':' in the named query
+}
+
+// compileNamedQuery converts a named query (e.g. "WHERE id=:id") into a positional one (e.g. "WHERE id=?")
+// and returns the parameters in order of appearance. It follows sqlx's conventions: "::" is an escaped ':'
+// everywhere (including string literals, quoted identifiers and comments, e.g. "'12::30'" becomes "'12:30'") and a
+// parameter name consists of letters, digits, '_' and '.'. Unlike sqlx, a ':' not followed by a letter or '_'
+// is kept as is (e.g. ":="), and no parameter is recognized in string literals, quoted identifiers and comments.
+//
+// NOTE: Statements with 'use_template' var are compiled by sqlx at runtime, which recognizes parameters in string
+// literals as well (e.g. "'12:30'"), so use "::" for literal ':' to get the same query on both paths.
+func compileNamedQuery(s string) (query string, params []namedParam) {
+
+	buf := make([]byte, 0, len(s))
+
+	// NOTE: Parameters are only recognized in consecutive runs of other tokens.
+	start := 0
+	for _, token := range lexSQL(s) {
+		switch token.kind {
+		case sqlString, sqlQuoted, sqlComment:
+			buf, params = compileNamedParams(s, start, token.offset, buf, params)
+			buf = append(buf, strings.Replace(token.text, "::", ":", -1)...)
+			start = token.offset + len(token.text)
+		}
+	}
+	buf, params = compileNamedParams(s, start, len(s), buf, params)
+
+	return string(buf), params
+
+}
+
+// compileNamedParams compiles s[start:end] into buf.
+func compileNamedParams(s string, start, end int, buf []byte, params []namedParam) ([]byte, []namedParam) {
+
+	for i := start; i < end; i++ {
+
+		c := s[i]
+		if c != ':' {
+			buf = append(buf, c)
+			continue
+		}
+
+		// Escaped ':'
+		if i+1 < end && s[i+1] == ':' {
+			buf = append(buf, ':')
+			i++
+			continue
+		}
+
+		// Not a parameter.
+		if i+1 >= end || !isNameStartByte(s[i+1]) {
+			buf = append(buf, c)
+			continue
+		}
+
+		j := i + 1
+		for j < end && isNameByte(s[j]) {
+			j++
+		}
+
+		params = append(params, namedParam{
+			name:   s[i+1 : j],
+			offset: i,
+		})
+		buf = append(buf, '?')
+		i = j - 1
+
+	}
+
+	return buf, params
+
+}
+
+func isNameStartByte(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isNameByte(c byte) bool {
+	return isNameStartByte(c) || (c >= '0' && c <= '9') || c == '.'
+}
+
+// textPos converts a byte offset in s into 1-based line and column numbers.
+func textPos(s string, offset int) (line, col int) {
+	line, col = 1, 1
+	for i := 0; i < offset && i < len(s); i++ {
+		if s[i] == '\n' {
+			line += 1
+			col = 1
+		} else {
+			col += 1
+		}
+	}
+	return
+}
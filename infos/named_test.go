@@ -0,0 +1,42 @@
+package infos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileNamedQuery(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, testCase := range []struct {
+		Named      string
+		Positional string
+		Names      []string
+	}{
+		{"SELECT 1", "SELECT 1", nil},
+		{"WHERE id=:id", "WHERE id=?", []string{"id"}},
+		{"WHERE a=:a AND b=:b_2 OR a=:a", "WHERE a=? AND b=? OR a=?", []string{"a", "b_2", "a"}},
+		{"WHERE x IN (:x.y)", "WHERE x IN (?)", []string{"x.y"}},
+		{"SELECT '::a'", "SELECT ':a'", nil},
+		{"SELECT 'a:b', \"c:d\", `e:f` FROM t WHERE x=:x", "SELECT 'a:b', \"c:d\", `e:f` FROM t WHERE x=?", []string{"x"}},
+		{"SELECT 'it''s :a' -- :b\n, /* :c */ :d # :e", "SELECT 'it''s :a' -- :b\n, /* :c */ ? # :e", []string{"d"}},
+		{"SELECT ':x", "SELECT ':x", nil},
+		{"SET @v := 1", "SET @v := 1", nil},
+		{"SELECT '12:30'", "SELECT '12:30'", nil},
+		{"UPDATE blog SET title='12::30' WHERE id=:id", "UPDATE blog SET title='12:30' WHERE id=?", []string{"id"}},
+		{"SELECT `a::b` -- c::d\n", "SELECT `a:b` -- c:d\n", nil},
+		{"SELECT :", "SELECT :", nil},
+	} {
+		positional, params := compileNamedQuery(testCase.Named)
+		names := []string(nil)
+		for _, param := range params {
+			names = append(names, param.name)
+			assert.Equal(byte(':'), testCase.Named[param.offset], "Named query: %+q", testCase.Named)
+		}
+		assert.Equal(testCase.Positional, positional, "Named query: %+q", testCase.Named)
+		assert.Equal(testCase.Names, names, "Named query: %+q", testCase.Named)
+	}
+
+}
@@ -0,0 +1,235 @@
+package infos
+
+import (
+	"github.com/huangjunwen/sqlw/datasrc"
+)
+
+var (
+	// Aggregate functions.
+	aggregateFuncs = map[string]struct{}{
+		"COUNT": {}, "SUM": {}, "MIN": {}, "MAX": {}, "AVG": {}, "GROUP_CONCAT": {}, "BIT_AND": {}, "BIT_OR": {},
+		"BIT_XOR": {}, "STD": {}, "STDDEV": {}, "VARIANCE": {},
+	}
+
+	// Select modifiers may appear before the first select list item.
+	selectModifiers = map[string]struct{}{
+		"ALL": {}, "DISTINCT": {}, "DISTINCTROW": {}, "HIGH_PRIORITY": {}, "STRAIGHT_JOIN": {},
+		"SQL_SMALL_RESULT": {}, "SQL_BIG_RESULT": {}, "SQL_BUFFER_RESULT": {}, "SQL_CACHE": {},
+		"SQL_NO_CACHE": {}, "SQL_CALC_FOUND_ROWS": {},
+	}
+
+	// Keywords ending a select list.
+	selectListEnds = map[string]struct{}{
+		"FROM": {}, "INTO": {}, "UNION": {}, "WHERE": {}, "GROUP": {}, "HAVING": {}, "WINDOW": {}, "ORDER": {},
+		"LIMIT": {}, "FOR": {}, "LOCK": {},
+	}
+)
+
+// selectList returns the items of the main select list of a SELECT query as significant tokens, and the
+// parenthesis depth of the main SELECT. The main SELECT is the first outermost one, e.g. the one following CTE
+// definitions. It returns nil if the select list can't be found.
+func selectList(tokens []sqlToken, depths []int) ([][]sqlToken, int) {
+
+	main := -1
+	for i, token := range tokens {
+		if token.keyword() == "SELECT" && (main < 0 || depths[i] < depths[main]) {
+			main = i
+		}
+	}
+	if main < 0 {
+		return nil, -1
+	}
+
+	depth := depths[main]
+	items := [][]sqlToken{}
+	item := []sqlToken{}
+
+loop:
+	for i := main + 1; i < len(tokens); i++ {
+		token := tokens[i]
+		if depths[i] < depth {
+			// ")" of a parenthesized SELECT.
+			break
+		}
+		if depths[i] == depth {
+			switch {
+			case token.kind == sqlPunct && token.text == ",":
+				items = append(items, item)
+				item = []sqlToken{}
+				continue
+			case token.kind == sqlPunct && token.text == ";":
+				break loop
+			}
+			keyword := token.keyword()
+			if _, found := selectListEnds[keyword]; found {
+				break loop
+			}
+			if _, found := selectModifiers[keyword]; found && len(items) == 0 && len(item) == 0 {
+				continue
+			}
+		}
+		item = append(item, token)
+	}
+
+	return append(items, item), depth
+
+}
+
+// stripAlias strips the alias of a select list item: "expr AS alias" or "expr alias".
+func stripAlias(item []sqlToken) []sqlToken {
+	n := len(item)
+	if n < 2 {
+		return item
+	}
+	last, prev := item[n-1], item[n-2]
+	if !last.isIdent() && last.kind != sqlString {
+		return item
+	}
+	if prev.keyword() == "AS" {
+		return item[:n-2]
+	}
+	// Implicit alias follows an identifier, a string or a parenthesized expression.
+	if prev.isIdent() || prev.kind == sqlString || (prev.kind == sqlPunct && prev.text == ")") {
+		return item[:n-1]
+	}
+	return item
+}
+
+// columnRef returns the table reference ("" if not qualified) and column name if the select list item (without
+// alias) is a plain column reference: "col", "tbl.col" or "`tbl`.`col`".
+func columnRef(item []sqlToken) (tableRef string, columnName string, ok bool) {
+	switch {
+	case len(item) == 1 && item[0].isIdent():
+		return "", item[0].ident(), true
+	case len(item) == 3 && item[0].isIdent() && item[1].text == "." && item[2].isIdent():
+		return item[0].ident(), item[2].ident(), true
+	}
+	return "", "", false
+}
+
+// aggregateFunc returns the upper cased aggregate function name if the select list item (without alias) is a single
+// aggregate, e.g. "SUM(amount)". It returns "" otherwise.
+func aggregateFunc(item []sqlToken) string {
+	if len(item) < 3 || item[1].text != "(" || item[len(item)-1].text != ")" {
+		return ""
+	}
+	fn := item[0].keyword()
+	if _, found := aggregateFuncs[fn]; !found {
+		return ""
+	}
+
+	// The '(' must match the last ')'.
+	depth := 0
+	for i, token := range item[1:] {
+		if token.kind != sqlPunct {
+			continue
+		}
+		switch token.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				if i+2 != len(item) {
+					return ""
+				}
+				return fn
+			}
+		}
+	}
+	return ""
+}
+
+// outerJoinedRefs returns table names/aliases on the nullable side of outer joins in the table references.
+// e.g. "b" in "user u LEFT JOIN blog b ON ..." and "u" in "user u RIGHT JOIN blog b ON ...".
+func outerJoinedRefs(refs []tableRef) map[string]bool {
+	ret := map[string]bool{}
+	for k, ref := range refs {
+		nullables := []tableRef{}
+		switch ref.outerJoin {
+		case "LEFT":
+			// The joined table.
+			nullables = refs[k : k+1]
+		case "RIGHT":
+			// All preceding tables.
+			nullables = refs[:k]
+		}
+		for _, nullable := range nullables {
+			ret[nullable.tableName] = true
+			if nullable.alias != "" {
+				ret[nullable.alias] = true
+			}
+		}
+	}
+	return ret
+}
+
+// analyzeResultColumns fills the origin (if not provided by driver) and adjusts data type and nullability of result
+// columns by analyzing the select list of the query. A column reference inherits data type and nullability from its
+// table column, unless the table is on the nullable side of an outer join, in which case it is nullable. Aggregates
+// except COUNT are nullable (e.g. MAX of an empty set). Directives can override the result later in
+// ProcessQueryResultColumns.
+//
+// Only table references of the main SELECT are used to resolve column references, table references in subqueries
+// are ignored.
+func analyzeResultColumns(db *DBInfo, query string, resultCols []*datasrc.Column) {
+
+	tokens := significantTokens(query)
+	items, depth := selectList(tokens, tokenDepths(tokens))
+	if len(items) != len(resultCols) {
+		// e.g. "SELECT *"
+		return
+	}
+
+	refs := []tableRef{}
+	for _, ref := range findTableRefs(query) {
+		if ref.depth == depth {
+			refs = append(refs, ref)
+		}
+	}
+	tables := refTables(db, refs)
+	outerJoined := outerJoinedRefs(refs)
+
+	for i, item := range items {
+		resultCol := resultCols[i]
+		item = stripAlias(item)
+
+		// Aggregates.
+		if fn := aggregateFunc(item); fn != "" {
+			resultCol.HasNullable = true
+			resultCol.Nullable = fn != "COUNT"
+			continue
+		}
+
+		// Column references.
+		tableRef, columnName, ok := columnRef(item)
+		if !ok {
+			continue
+		}
+
+		column := resolveColumnRef(tables, tableRef, columnName)
+		if column == nil {
+			continue
+		}
+		if resultCol.OrgTable == "" {
+			resultCol.OrgTable = column.Table().TableName()
+			resultCol.OrgName = column.ColumnName()
+		}
+		// Table column's data type is more precise than the one from result metadata.
+		if column.Col().DataType != "" {
+			resultCol.DataType = column.Col().DataType
+		}
+
+		if tableRef == "" {
+			tableRef = column.Table().TableName()
+		}
+		if outerJoined[tableRef] {
+			resultCol.HasNullable = true
+			resultCol.Nullable = true
+		} else if column.Col().HasNullable {
+			resultCol.HasNullable = true
+			resultCol.Nullable = column.Col().Nullable
+		}
+	}
+
+}
@@ -0,0 +1,99 @@
+package infos_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultColOrigin(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := newTestFixture(t)
+	defer fixture.Close()
+
+	user, blog := fixture.DB.TableByName("user"), fixture.DB.TableByName("blog")
+
+	stmt, err := fixture.Stmt(`<stmt name="A">
+  SELECT DISTINCT u.email, ` + "`b`.`title`" + ` AS t, name, b.id + 1 AS next_id FROM user u JOIN blog b ON b.user_id=u.id
+</stmt>`)
+	if assert.NoError(err) {
+		assert.Equal(user.ColumnByName("email"), stmt.ResultColOrigin(0))
+		assert.Equal(blog.ColumnByName("title"), stmt.ResultColOrigin(1))
+		assert.Equal(user.ColumnByName("name"), stmt.ResultColOrigin(2))
+		assert.Nil(stmt.ResultColOrigin(3))
+		assert.Nil(stmt.ResultColOrigin(4))
+	}
+
+	// Unqualified column unique among joined tables.
+	stmt, err = fixture.Stmt(`<stmt name="A">SELECT user.id AS uid, title FROM user JOIN blog ON blog.user_id=user.id</stmt>`)
+	if assert.NoError(err) {
+		assert.Equal(user.ColumnByName("id"), stmt.ResultColOrigin(0))
+		assert.Equal(blog.ColumnByName("title"), stmt.ResultColOrigin(1))
+	}
+
+	// Aliases, quoted identifiers and subqueries: tables in subqueries are not used to resolve select list items.
+	stmt, err = fixture.Stmt(`<stmt name="A">
+  SELECT ` + "`u`.`name` `select`, u.email 'e, f', (SELECT MAX(b.title) FROM blog b WHERE b.user_id=u.id) latest, `birthday`" + `
+  FROM user u WHERE u.id IN (SELECT blog.user_id FROM blog)
+</stmt>`)
+	if assert.NoError(err) {
+		assert.Equal(user.ColumnByName("name"), stmt.ResultColOrigin(0))
+		assert.Equal(user.ColumnByName("email"), stmt.ResultColOrigin(1))
+		assert.Nil(stmt.ResultColOrigin(2))
+		assert.Equal(user.ColumnByName("birthday"), stmt.ResultColOrigin(3))
+	}
+
+}
+
+func TestResultColNullable(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := newTestFixture(t)
+	defer fixture.Close()
+
+	nullables := func(s string) []bool {
+		stmt, err := fixture.Stmt(s)
+		if !assert.NoError(err) {
+			return nil
+		}
+		ret := []bool{}
+		for _, col := range stmt.ResultCols() {
+			assert.True(col.HasNullable)
+			ret = append(ret, col.Nullable)
+		}
+		return ret
+	}
+
+	// Nullability of table columns.
+	assert.Equal([]bool{false, true}, nullables(`<stmt name="A">SELECT id, email FROM user</stmt>`))
+
+	// The nullable side of outer joins.
+	assert.Equal([]bool{false, true, true}, nullables(`<stmt name="A">
+  SELECT u.id, b.id AS blog_id, b.title FROM user u LEFT OUTER JOIN blog b ON b.user_id=u.id
+</stmt>`))
+	assert.Equal([]bool{true, false}, nullables(`<stmt name="A">
+  SELECT user.name, blog.title FROM user RIGHT JOIN blog ON blog.user_id=user.id
+</stmt>`))
+	assert.Equal([]bool{false, false}, nullables(`<stmt name="A">
+  SELECT u.name, b.title FROM user u JOIN blog b ON b.user_id=u.id
+</stmt>`))
+
+	// Outer joins in subqueries don't affect the main SELECT.
+	assert.Equal([]bool{false}, nullables(`<stmt name="A">
+  SELECT user.name FROM user WHERE user.id IN (SELECT blog.user_id FROM blog LEFT JOIN user ON user.id=blog.user_id)
+</stmt>`))
+
+	// Aggregates except COUNT are nullable.
+	assert.Equal([]bool{false, true, true}, nullables(`<stmt name="A">
+  SELECT COUNT(*) AS n, MAX(id) AS max_id, SUM(id) total FROM user
+</stmt>`))
+
+	// Nullable wildcard.
+	assert.Equal([]bool{false, false, true, true, true, true, true}, nullables(`<stmt name="A">
+  SELECT u.id, u.name, <wc table="blog" as="b" nullable="true" /> FROM user u LEFT JOIN blog b ON b.user_id=u.id
+</stmt>`))
+
+}
@@ -0,0 +1,186 @@
+package infos
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// SourcePos is the position of an xml element in a statement file.
+type SourcePos struct {
+	File string
+	Line int // 1-based
+	Col  int // 1-based, in bytes
+}
+
+// SourceMap records source positions of xml elements in statement files.
+type SourceMap struct {
+	positions map[*etree.Element]SourcePos
+}
+
+// SourceError is an error located at a source position, it is formatted as "file.xml:12:5: <wc>: message".
+type SourceError struct {
+	Pos SourcePos
+	Tag string
+	Err error
+}
+
+// ErrorList is a list of errors. It is returned when processing continues after errors so that all of them are
+// reported in one run.
+type ErrorList []error
+
+// Valid returns true if the position is known.
+func (pos SourcePos) Valid() bool {
+	return pos.Line > 0
+}
+
+// String returns "file:line:col" or "" if the position is unknown.
+func (pos SourcePos) String() string {
+	if !pos.Valid() {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Col)
+}
+
+// NewSourceMap creates an empty SourceMap.
+func NewSourceMap() *SourceMap {
+	return &SourceMap{
+		positions: map[*etree.Element]SourcePos{},
+	}
+}
+
+// SetPos sets the source position of an xml element. It does nothing if m is nil.
+func (m *SourceMap) SetPos(elem *etree.Element, pos SourcePos) {
+	if m == nil {
+		return
+	}
+	m.positions[elem] = pos
+}
+
+// Pos returns the source position of an xml element. It returns an invalid position if m is nil or not found.
+func (m *SourceMap) Pos(elem *etree.Element) SourcePos {
+	if m == nil {
+		return SourcePos{}
+	}
+	return m.positions[elem]
+}
+
+// Sources sets the source map used to locate errors of the statement, e.g. "blog.xml:12:5: <wc>: Table \"blogs\" not
+// found".
+func Sources(sources *SourceMap) StmtOption {
+	return func(info *StmtInfo) {
+		info.sources = sources
+	}
+}
+
+// ReadXMLFile reads an xml statement file from content and records positions of all its elements.
+func (m *SourceMap) ReadXMLFile(fileName string, content []byte) (*etree.Document, error) {
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(content); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+
+	// NOTE: etree does not track positions, decode again to find the offset of each start element, which are in the
+	// same order as elements in the document.
+	offsets := []int64{}
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		offset := decoder.InputOffset()
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", fileName, err)
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	i := 0
+	var walk func(*etree.Element)
+	walk = func(elem *etree.Element) {
+		if i < len(offsets) {
+			line, col := textPos(string(content), int(offsets[i]))
+			m.SetPos(elem, SourcePos{
+				File: fileName,
+				Line: line,
+				Col:  col,
+			})
+		}
+		i++
+		for _, child := range elem.ChildElements() {
+			walk(child)
+		}
+	}
+	for _, elem := range doc.ChildElements() {
+		walk(elem)
+	}
+
+	return doc, nil
+
+}
+
+func (err *SourceError) Error() string {
+	msg := err.Err.Error()
+	if err.Tag != "" {
+		msg = "<" + err.Tag + ">: " + msg
+	}
+	if err.Pos.Valid() {
+		msg = err.Pos.String() + ": " + msg
+	}
+	return msg
+}
+
+// Add appends err to the list, nested ErrorList is flattened. nil is ignored.
+func (list *ErrorList) Add(err error) {
+	switch e := err.(type) {
+	case nil:
+	case ErrorList:
+		*list = append(*list, e...)
+	default:
+		*list = append(*list, err)
+	}
+}
+
+// Err returns nil if the list is empty, the only error if it has one, or the list itself.
+func (list ErrorList) Err() error {
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	default:
+		return list
+	}
+}
+
+// Error returns all errors, one per line.
+func (list ErrorList) Error() string {
+	msgs := []string{}
+	for _, err := range list {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// sourceError wraps err with the position of elem unless it is already located.
+func sourceError(sources *SourceMap, elem *etree.Element, err error) error {
+	switch err.(type) {
+	case nil:
+		return nil
+	case *SourceError, ErrorList:
+		return err
+	}
+	return &SourceError{
+		Pos: sources.Pos(elem),
+		Tag: elem.Tag,
+		Err: err,
+	}
+}
@@ -0,0 +1,35 @@
+package infos
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadXMLFile(t *testing.T) {
+
+	assert := assert.New(t)
+
+	sources := NewSourceMap()
+	doc, err := sources.ReadXMLFile("blog.xml", []byte("<stmt name=\"A\">\n  SELECT <wc table=\"blog\" />\n</stmt>\n<stmt name=\"B\">\n  <include ref=\"x\"/></stmt>"))
+	assert.NoError(err)
+
+	stmts := doc.ChildElements()
+	assert.Len(stmts, 2)
+	assert.Equal("blog.xml:1:1", sources.Pos(stmts[0]).String())
+	assert.Equal("blog.xml:2:10", sources.Pos(stmts[0].ChildElements()[0]).String())
+	assert.Equal("blog.xml:4:1", sources.Pos(stmts[1]).String())
+	assert.Equal("blog.xml:5:3", sources.Pos(stmts[1].ChildElements()[0]).String())
+
+	err = sourceError(sources, stmts[0].ChildElements()[0], fmt.Errorf("Table %+q not found", "blogs"))
+	assert.EqualError(err, "blog.xml:2:10: <wc>: Table \"blogs\" not found")
+	assert.Equal(err, sourceError(sources, stmts[0], err))
+
+	errs := ErrorList{}
+	assert.NoError(errs.Err())
+	errs.Add(err)
+	errs.Add(ErrorList{err, err})
+	assert.Len(errs, 3)
+
+}
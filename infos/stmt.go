@@ -3,7 +3,6 @@ package infos
 import (
 	"fmt"
 	"strings"
-	"unicode"
 
 	"github.com/beevik/etree"
 	"github.com/huangjunwen/sqlw/datasrc"
@@ -11,12 +10,33 @@ import (
 
 // StmtInfo contains information of a statement.
 type StmtInfo struct {
-	stmtType   string // 'SELECT'/'UPDATE'/'INSERT'/'DELETE'
+	stmtType   string // 'SELECT'/'UPDATE'/'INSERT'/'DELETE'/'REPLACE'/'CALL'
 	stmtName   string
 	text       string
 	resultCols []*datasrc.Column // for SELECT stmt only
+	dynNodes   []*DynNode        // registered dynamic directives indexed by id
+	dynTree    []*DynNode        // for dynamic stmt only
+
+	hasLocking       bool
+	isMultiStatement bool
+
+	pos            SourcePos
+	db             *DBInfo
+	sources        *SourceMap
+	directiveElems map[Directive]*etree.Element // terminal directive -> its xml element
+	locals         map[interface{}]interface{}  // directive locals
+	warnings       ErrorList
+}
+
+// StmtOption is an optional argument of NewStmtInfo.
+type StmtOption func(*StmtInfo)
 
-	locals map[interface{}]interface{} // directive locals
+// Locals sets a directive local before the statement is processed, which is used to pass information to
+// directives.
+func Locals(key, val interface{}) StmtOption {
+	return func(info *StmtInfo) {
+		info.SetLocals(key, val)
+	}
 }
 
 // NewStmtInfo creates a new StmtInfo from an xml element, example statement xml element:
@@ -27,25 +47,34 @@ type StmtInfo struct {
 //   </stmt>
 //
 // A statement xml element contains SQL statement fragments and special directives.
-func NewStmtInfo(loader *datasrc.Loader, db *DBInfo, stmtElem *etree.Element) (*StmtInfo, error) {
+//
+// Errors of all directives in the same processing step are reported in an ErrorList.
+func NewStmtInfo(loader *datasrc.Loader, db *DBInfo, stmtElem *etree.Element, opts ...StmtOption) (*StmtInfo, error) {
 
 	info := &StmtInfo{
-		locals: map[interface{}]interface{}{},
+		db:             db,
+		directiveElems: map[Directive]*etree.Element{},
+		locals:         map[interface{}]interface{}{},
+	}
+	for _, opt := range opts {
+		opt(info)
 	}
+	sources := info.sources
+	info.pos = sources.Pos(stmtElem)
 
 	if stmtElem.Tag != "stmt" {
-		return nil, fmt.Errorf("Expect <stmt> but got <%s>", stmtElem.Tag)
+		return nil, sourceError(sources, stmtElem, fmt.Errorf("Expect <stmt> but got <%s>", stmtElem.Tag))
 	}
 
 	// Name attribute
 	info.stmtName = stmtElem.SelectAttrValue("name", "")
 	if info.stmtName == "" {
-		return nil, fmt.Errorf("Missing 'name' attribute of <%s>", info.stmtType)
+		return nil, sourceError(sources, stmtElem, fmt.Errorf("Missing 'name' attribute of <%s>", stmtElem.Tag))
 	}
 
 	// Process it.
 	if err := info.process(loader, db, stmtElem); err != nil {
-		return nil, err
+		return nil, sourceError(sources, stmtElem, err)
 	}
 
 	return info, nil
@@ -64,7 +93,7 @@ func (info *StmtInfo) token2TerminalDirectives(loader *datasrc.Loader, db *DBInf
 	case *etree.Element:
 		factory := directiveFactories[tok.Tag]
 		if factory == nil {
-			return nil, fmt.Errorf("Unknown directive <%s>", tok.Tag)
+			return nil, info.directiveError(tok, fmt.Errorf("Unknown directive"))
 		}
 		directive = factory()
 
@@ -74,31 +103,37 @@ func (info *StmtInfo) token2TerminalDirectives(loader *datasrc.Loader, db *DBInf
 
 	// Initialize
 	if err := directive.Initialize(loader, db, info, token); err != nil {
-		return nil, err
+		return nil, info.directiveError(token, err)
 	}
 
 	// Expand directive recursively if it is NonterminalDirective.
 	switch d := directive.(type) {
 
 	case TerminalDirective:
+		if elem, ok := token.(*etree.Element); ok {
+			info.directiveElems[d] = elem
+		}
 		return []TerminalDirective{d}, nil
 
 	case NonterminalDirective:
 		ts, err := d.Expand()
 		if err != nil {
-			return nil, err
+			return nil, info.directiveError(token, err)
 		}
 
+		// NOTE: Continue after errors to report all of them.
 		ret := []TerminalDirective{}
+		errs := ErrorList{}
 		for _, t := range ts {
 			terminalDirectives, err := info.token2TerminalDirectives(loader, db, t)
 			if err != nil {
-				return nil, err
+				errs.Add(err)
+				continue
 			}
 			ret = append(ret, terminalDirectives...)
 		}
 
-		return ret, nil
+		return ret, errs.Err()
 
 	default:
 		panic(fmt.Errorf("Directive must be either TerminalDirective or NonterminalDirective"))
@@ -111,16 +146,23 @@ func (info *StmtInfo) process(loader *datasrc.Loader, db *DBInfo, stmtElem *etre
 	// Convert stmtElem to a list of TerminalDirective
 	directives := []TerminalDirective{}
 
+	errs := ErrorList{}
+
 	for _, token := range stmtElem.Child {
 
 		ds, err := info.token2TerminalDirectives(loader, db, token)
 		if err != nil {
-			return err
+			errs.Add(err)
+			continue
 		}
 		directives = append(directives, ds...)
 
 	}
 
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
 	// Construct query.
 	query := ""
 
@@ -131,53 +173,98 @@ func (info *StmtInfo) process(loader *datasrc.Loader, db *DBInfo, stmtElem *etre
 
 			fragment, err := directive.QueryFragment()
 			if err != nil {
-				return err
+				errs.Add(info.directiveError(directive, err))
+				continue
 			}
 			fragments = append(fragments, fragment)
 
 		}
 
+		if err := errs.Err(); err != nil {
+			return err
+		}
+
 		query = strings.TrimSpace(strings.Join(fragments, ""))
 
 	}
 
+	// For dynamic statement, expand to concrete variants for branch combinations. The first one takes all branches.
+	queries := []string{query}
+	if len(info.dynNodes) != 0 {
+		tree, err := parseDyn(query, info.dynNodes)
+		if err != nil {
+			return err
+		}
+		queries = dynVariants(tree)
+		query = queries[0]
+	}
+
 	// Determine statement type.
 	{
 
-		sp := strings.IndexFunc(query, unicode.IsSpace)
-		if sp < 0 {
-			return fmt.Errorf("Can't determine statement type for %+q", query)
-		}
-		verb := strings.ToUpper(query[:sp])
-		switch verb {
-		case "SELECT", "INSERT", "UPDATE", "DELETE", "REPLACE":
-		default:
-			return fmt.Errorf("Not supported statement type %+q", verb)
+		class, err := classifyStmt(query)
+		if err != nil {
+			return err
 		}
 
-		info.stmtType = verb
+		info.stmtType = class.verb
+		info.hasLocking = class.hasLocking
+		info.isMultiStatement = class.isMultiStatement
 
 	}
 
 	// If it's a SELECT statement, load query result columns.
 	if info.StmtType() == "SELECT" {
 
-		cols, err := loader.LoadQueryResultColumns(query)
+		cols, err := loader.LoadQueryResultColumns(limitZeroQuery(query))
 		if err != nil {
 			return err
 		}
 
+		// All variants must have the same result columns.
+		for _, q := range queries[1:] {
+			qCols, err := loader.LoadQueryResultColumns(limitZeroQuery(q))
+			if err != nil {
+				return fmt.Errorf("Dynamic variant %+q of statement %+q: %s", q, info.stmtName, err)
+			}
+			if !sameColumnNames(cols, qCols) {
+				return fmt.Errorf("Dynamic variant %+q of statement %+q has different result columns", q, info.stmtName)
+			}
+		}
+
+		// Analyze origin and nullability of columns.
+		analyzeResultColumns(db, query, cols)
+
 		// Process query result
 		for _, directive := range directives {
 			if err := directive.ProcessQueryResultColumns(&cols); err != nil {
-				return err
+				errs.Add(info.directiveError(directive, err))
 			}
 		}
+		if err := errs.Err(); err != nil {
+			return err
+		}
 
 		info.resultCols = cols
 
 	}
 
+	// Validate non-SELECT statement (all variants for dynamic statement) against the database.
+	//
+	// NOTE: The query text is validated instead of the final text, which may not be valid SQL (e.g. with
+	// "use_template" var or <in> directive).
+	if info.StmtType() != "SELECT" {
+		for _, q := range queries {
+			positional, _ := compileNamedQuery(q)
+			if err := loader.ValidateQuery(positional); err != nil {
+				if len(queries) > 1 {
+					return fmt.Errorf("Invalid dynamic variant %+q of statement %+q: %s", q, info.stmtName, err)
+				}
+				return fmt.Errorf("Invalid statement %+q: %s", info.stmtName, err)
+			}
+		}
+	}
+
 	// Final text
 	{
 
@@ -187,14 +274,115 @@ func (info *StmtInfo) process(loader *datasrc.Loader, db *DBInfo, stmtElem *etre
 
 			fragment, err := directive.Fragment()
 			if err != nil {
-				return err
+				errs.Add(info.directiveError(directive, err))
+				continue
 			}
 			fragments = append(fragments, fragment)
 
 		}
 
+		if err := errs.Err(); err != nil {
+			return err
+		}
+
 		info.text = strings.TrimSpace(strings.Join(fragments, ""))
 
+		if len(info.dynNodes) != 0 {
+			tree, err := parseDyn(info.text, info.dynNodes)
+			if err != nil {
+				return err
+			}
+			info.dynTree = tree
+		}
+
+	}
+
+	// Finalize directives.
+	for _, directive := range directives {
+		if d, ok := directive.(Finalizer); ok {
+			if err := d.Finalize(); err != nil {
+				errs.Add(info.directiveError(directive, err))
+			}
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	// Check named parameters against declared args.
+	if err := info.checkNamedParams(directives); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// directiveError locates err by the xml element of a directive, which is either a token or a terminal directive.
+// It returns err unchanged if the element is unknown, so that it is located by the statement.
+func (info *StmtInfo) directiveError(directive interface{}, err error) error {
+	var elem *etree.Element
+	switch d := directive.(type) {
+	case *etree.Element:
+		elem = d
+	case Directive:
+		elem = info.directiveElems[d]
+	}
+	if elem == nil {
+		return err
+	}
+	return sourceError(info.sources, elem, err)
+}
+
+func (info *StmtInfo) checkNamedParams(directives []TerminalDirective) error {
+
+	argDeclarers := []ArgDeclarer{}
+	argUsed := map[string]bool{}
+	for _, directive := range directives {
+		if d, ok := directive.(ArgDeclarer); ok {
+			argDeclarers = append(argDeclarers, d)
+			argUsed[d.DeclaredArgName()] = false
+		}
+	}
+
+	// NOTE: In dynamic statement, foreach items are declared as well, args in if tests, foreach
+	// collections and in lists are used.
+	for _, node := range info.dynNodes {
+		switch node.kind {
+		case "if":
+			for _, ident := range node.testIdents {
+				if _, found := argUsed[ident]; found {
+					argUsed[ident] = true
+				}
+			}
+		case "foreach":
+			if _, found := argUsed[node.collection]; !found {
+				return fmt.Errorf("Undeclared collection %+q in <foreach> directive in statement %+q", node.collection, info.stmtName)
+			}
+			argUsed[node.collection] = true
+			argUsed[node.item] = true
+		case "in":
+			if _, found := argUsed[node.collection]; !found {
+				return fmt.Errorf("Undeclared arg %+q in <in> directive in statement %+q", node.collection, info.stmtName)
+			}
+			argUsed[node.collection] = true
+		}
+	}
+
+	_, params := compileNamedQuery(info.text)
+	for _, param := range params {
+		// NOTE: "arg.field" refers to a field of arg.
+		argName := strings.SplitN(param.name, ".", 2)[0]
+		if _, found := argUsed[argName]; !found {
+			line, col := textPos(info.text, param.offset)
+			return fmt.Errorf("Undeclared named parameter %+q at %d:%d in statement %+q", param.name, line, col, info.stmtName)
+		}
+		argUsed[argName] = true
+	}
+
+	for _, d := range argDeclarers {
+		if argName := d.DeclaredArgName(); !argUsed[argName] {
+			info.AddWarning(info.directiveError(d, fmt.Errorf("Arg %+q is not used as named parameter in statement %+q", argName, info.stmtName)))
+		}
 	}
 
 	return nil
@@ -205,6 +393,23 @@ func (info *StmtInfo) Valid() bool {
 	return info != nil
 }
 
+// Pos returns the source position of the statement. It returns an invalid position if info is nil or unknown.
+func (info *StmtInfo) Pos() SourcePos {
+	if info == nil {
+		return SourcePos{}
+	}
+	return info.pos
+}
+
+// SourcePos returns the source position of an xml element (e.g. a directive) of the statement. It returns an invalid
+// position if info is nil or unknown.
+func (info *StmtInfo) SourcePos(elem *etree.Element) SourcePos {
+	if info == nil {
+		return SourcePos{}
+	}
+	return info.sources.Pos(elem)
+}
+
 // String is same as StmtName.
 func (info *StmtInfo) String() string {
 	return info.StmtName()
@@ -218,7 +423,8 @@ func (info *StmtInfo) StmtName() string {
 	return info.stmtName
 }
 
-// StmtType returns the statement type, one of "SELECT"/"UPDATE"/"INSERT"/"UPDATE". It returns "" if info is nil.
+// StmtType returns the statement type, one of "SELECT"/"UPDATE"/"INSERT"/"DELETE"/"REPLACE"/"CALL". For CTE it is
+// the main verb following the CTE definitions. It returns "" if info is nil.
 func (info *StmtInfo) StmtType() string {
 	if info == nil {
 		return ""
@@ -226,6 +432,24 @@ func (info *StmtInfo) StmtType() string {
 	return info.stmtType
 }
 
+// HasLocking returns true if the statement has a locking clause ("FOR UPDATE"/"FOR SHARE"/"LOCK IN SHARE MODE").
+// It returns false if info is nil.
+func (info *StmtInfo) HasLocking() bool {
+	if info == nil {
+		return false
+	}
+	return info.hasLocking
+}
+
+// IsMultiStatement returns true if the statement contains multiple statements separated by ';'.
+// It returns false if info is nil.
+func (info *StmtInfo) IsMultiStatement() bool {
+	if info == nil {
+		return false
+	}
+	return info.isMultiStatement
+}
+
 // Text returns the statment text. It returns "" if info is nil.
 func (info *StmtInfo) Text() string {
 	if info == nil {
@@ -234,6 +458,47 @@ func (info *StmtInfo) Text() string {
 	return info.text
 }
 
+// PositionalText returns the statement text with named parameters (e.g. ":userId") replaced by positional
+// placeholders ("?"), "::" in the text is unescaped to ":". It returns "" if info is nil.
+func (info *StmtInfo) PositionalText() string {
+	if info == nil {
+		return ""
+	}
+	query, _ := compileNamedQuery(info.text)
+	return query
+}
+
+// ParamNames returns the named parameters in the statement text in order of appearance,
+// one for each placeholder in PositionalText. It returns nil if info is nil.
+func (info *StmtInfo) ParamNames() []string {
+	if info == nil {
+		return nil
+	}
+	_, params := compileNamedQuery(info.text)
+	names := []string{}
+	for _, param := range params {
+		names = append(names, param.name)
+	}
+	return names
+}
+
+// IsDynamic returns true if the statement contains dynamic directives (<if>/<where>/<set>/<foreach>/<in>).
+// A dynamic statement's text is built at runtime from DynNodes.
+func (info *StmtInfo) IsDynamic() bool {
+	if info == nil {
+		return false
+	}
+	return len(info.dynNodes) != 0
+}
+
+// DynNodes returns the top level nodes of a dynamic statement. It returns nil if info is nil or it is not dynamic.
+func (info *StmtInfo) DynNodes() []*DynNode {
+	if info == nil {
+		return nil
+	}
+	return info.dynTree
+}
+
 // NumResultCol returns the number of result columns. It returns 0 if info is nil or it is not "SELECT" statement.
 func (info *StmtInfo) NumResultCol() int {
 	if info == nil {
@@ -250,6 +515,32 @@ func (info *StmtInfo) ResultCols() []*datasrc.Column {
 	return info.resultCols
 }
 
+// ResultColOrigin returns the table column which the i-th result column originates from. It returns nil if info is nil,
+// i is out of range or the origin is unknown (e.g. an expression).
+func (info *StmtInfo) ResultColOrigin(i int) *ColumnInfo {
+	if info == nil {
+		return nil
+	}
+	if i < 0 || i >= len(info.resultCols) {
+		return nil
+	}
+	col := info.resultCols[i]
+	return info.db.TableByName(col.OrgTable).ColumnByName(col.OrgName)
+}
+
+// AddWarning adds a warning of the statement. Warnings don't stop processing, they are reported by Warnings.
+func (info *StmtInfo) AddWarning(err error) {
+	info.warnings.Add(err)
+}
+
+// Warnings returns warnings found in processing the statement. It returns nil if info is nil or there is no warning.
+func (info *StmtInfo) Warnings() []error {
+	if info == nil {
+		return nil
+	}
+	return info.warnings
+}
+
 // Locals returns the associated value for the given key in StmtInfo's locals map.
 // This map is used by directives to store directive specific variables.
 func (info *StmtInfo) Locals(key interface{}) interface{} {
@@ -260,3 +551,15 @@ func (info *StmtInfo) Locals(key interface{}) interface{} {
 func (info *StmtInfo) SetLocals(key, val interface{}) {
 	info.locals[key] = val
 }
+
+func sameColumnNames(cols1, cols2 []*datasrc.Column) bool {
+	if len(cols1) != len(cols2) {
+		return false
+	}
+	for i := range cols1 {
+		if cols1[i].Name != cols2[i].Name {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package infos_test
+
+import (
+	"testing"
+
+	_ "github.com/huangjunwen/sqlw/infos/directives/arg"
+	_ "github.com/huangjunwen/sqlw/infos/directives/if"
+	_ "github.com/huangjunwen/sqlw/infos/directives/wc"
+	_ "github.com/huangjunwen/sqlw/infos/directives/where"
+	"github.com/huangjunwen/sqlw/internal/fakedb"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestFixture creates a fake database with "user" and "blog" tables.
+func newTestFixture(t *testing.T) *fakedb.Fixture {
+	return fakedb.NewFixture(t,
+		&fakedb.Table{
+			Name: "user",
+			Columns: []fakedb.Column{
+				{Name: "id", DataType: "int32"},
+				{Name: "name", DataType: "string"},
+				{Name: "email", DataType: "string", Nullable: true},
+				{Name: "birthday", DataType: "time", Nullable: true},
+			},
+			Primary: []string{"id"},
+			AutoInc: "id",
+		},
+		&fakedb.Table{
+			Name: "blog",
+			Columns: []fakedb.Column{
+				{Name: "id", DataType: "int32"},
+				{Name: "user_id", DataType: "int32"},
+				{Name: "title", DataType: "string"},
+				{Name: "content", DataType: "string", Nullable: true},
+				{Name: "published", DataType: "bool"},
+			},
+			Primary: []string{"id"},
+			AutoInc: "id",
+		},
+	)
+}
+
+func TestStmtClass(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := newTestFixture(t)
+	defer fixture.Close()
+
+	for _, testCase := range []struct {
+		Stmt             string
+		StmtType         string
+		HasLocking       bool
+		IsMultiStatement bool
+	}{
+		{`<stmt name="A">SELECT id FROM user</stmt>`, "SELECT", false, false},
+		{`<stmt name="A">SELECT id FROM user WHERE name='FOR UPDATE' FOR UPDATE</stmt>`, "SELECT", true, false},
+		{`<stmt name="A">SELECT id FROM user LOCK IN SHARE MODE</stmt>`, "SELECT", true, false},
+		{"<stmt name=\"A\">SELECT id FROM user; -- trailing comment\n</stmt>", "SELECT", false, false},
+		{"<stmt name=\"A\">\n  SELECT id FROM user FOR UPDATE;\n  DELETE FROM user\n</stmt>", "SELECT", true, true},
+		{`<stmt name="A">DELETE FROM blog; DELETE FROM user</stmt>`, "DELETE", false, true},
+	} {
+		stmt, err := fixture.Stmt(testCase.Stmt)
+		if !assert.NoError(err, "Stmt: %+q", testCase.Stmt) {
+			continue
+		}
+		assert.Equal(testCase.StmtType, stmt.StmtType(), "Stmt: %+q", testCase.Stmt)
+		assert.Equal(testCase.HasLocking, stmt.HasLocking(), "Stmt: %+q", testCase.Stmt)
+		assert.Equal(testCase.IsMultiStatement, stmt.IsMultiStatement(), "Stmt: %+q", testCase.Stmt)
+	}
+
+	// Result columns of a multi-statement are those of the first statement.
+	stmt, err := fixture.Stmt(`<stmt name="A">SELECT id, name FROM user; SELECT id FROM blog</stmt>`)
+	if assert.NoError(err) {
+		assert.Equal(2, stmt.NumResultCol())
+	}
+
+}
+
+func TestValidateStmt(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := newTestFixture(t)
+	defer fixture.Close()
+
+	_, err := fixture.Stmt(`<stmt name="RenameUser">
+  <arg name="id" type="int" />
+  UPDATE users SET name='x' WHERE id=:id
+</stmt>`)
+	assert.EqualError(err, `<stmt>: Invalid statement "RenameUser": Table "users" doesn't exist`)
+
+	_, err = fixture.Stmt(`<stmt name="DeleteUsers">
+  <arg name="name" type="*string" />
+  DELETE FROM user <where><if test="name != nil">AND id IN (SELECT user_id FROM blogs WHERE title=:name)</if></where>
+</stmt>`)
+	assert.EqualError(err, `<stmt>: Invalid dynamic variant "DELETE FROM user  WHERE id IN (SELECT user_id FROM blogs WHERE title=:name)" of statement "DeleteUsers": Table "blogs" doesn't exist`)
+
+	_, err = fixture.Stmt(`<stmt name="RenameUser">
+  <arg name="id" type="int" />
+  UPDATE user SET name='x' WHERE id=:id
+</stmt>`)
+	assert.NoError(err)
+
+}
+
+func TestNamedParams(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := newTestFixture(t)
+	defer fixture.Close()
+
+	_, err := fixture.Stmt(`<stmt name="BlogsByUser">
+  <arg name="userId" type="int" />
+  SELECT id FROM blog WHERE user_id=:userID
+</stmt>`)
+	assert.EqualError(err, `<stmt>: Undeclared named parameter "userID" at 1:35 in statement "BlogsByUser"`)
+
+	stmt, err := fixture.Stmt(`<stmt name="BlogsByUser">
+  <arg name="userId" type="int" />
+  <arg name="title" type="string" />
+  SELECT id FROM blog WHERE user_id=:userId
+</stmt>`)
+	if assert.NoError(err) {
+		if assert.Len(stmt.Warnings(), 1) {
+			assert.EqualError(stmt.Warnings()[0], `<arg>: Arg "title" is not used as named parameter in statement "BlogsByUser"`)
+		}
+	}
+
+	stmt, err = fixture.Stmt(`<stmt name="BlogsByUser">
+  <arg name="user" type="*User" />
+  SELECT id FROM blog WHERE user_id=:user.id
+</stmt>`)
+	if assert.NoError(err) {
+		assert.Len(stmt.Warnings(), 0)
+	}
+
+}
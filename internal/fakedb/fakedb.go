@@ -0,0 +1,420 @@
+// Package fakedb is an in-memory database driver for tests which don't need a real database server. It only knows
+// table definitions: result columns of a SELECT query are derived from its select list and queries referencing
+// unknown tables or columns are rejected. Query syntax is not checked, expressions in select list should have aliases.
+package fakedb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/huangjunwen/sqlw/datasrc"
+)
+
+// Column is a table column.
+type Column struct {
+	Name     string
+	DataType string // e.g. "int32", see DataTypes of mysql driver
+	Nullable bool
+	Comment  string
+}
+
+// Table is a table definition.
+type Table struct {
+	Name    string
+	Columns []Column
+	Primary []string // primary key column names
+	AutoInc string   // auto increment column name
+}
+
+type fakeDriver struct{}
+
+type fakeConn struct {
+	tables []*Table
+}
+
+type fakeStmt struct{}
+
+var (
+	_ datasrc.Driver            = fakeDriver{}
+	_ datasrc.DriverWithAutoInc = fakeDriver{}
+)
+
+var (
+	mu        sync.Mutex
+	databases = map[string][]*Table{}
+
+	// Matches keywords before table references: "FROM", "JOIN", "UPDATE", "INTO"
+	tableRefKeywordRe = regexp.MustCompile("(?i)\\b(?:FROM|JOIN|UPDATE|INTO)\\s+")
+
+	// Matches a table reference after keyword: "user u", "`blog` AS b"
+	tableRefRe = regexp.MustCompile("(?i)^(`[^`]+`|\\w+)(?:\\s+(?:AS\\s+)?(`[^`]+`|\\w+))?")
+
+	// Matches a select list item which is a column reference: "`u`.`name` AS n", "name"
+	colRefRe = regexp.MustCompile("(?is)^(?:(`[^`]+`|\\w+)\\s*\\.\\s*)?(`[^`]+`|\\w+|\\*)$")
+
+	// Matches the first column reference in ORDER BY clause: "ORDER BY `u`.`name`"
+	orderByRe = regexp.MustCompile("(?i)\\bORDER\\s+BY\\s+(?:(`[^`]+`|\\w+)\\s*\\.\\s*)?(`[^`]+`|\\w+)")
+
+	// Matches an alias at the end of a select list item: " AS n"
+	aliasRe = regexp.MustCompile("(?is)^(.*?)\\s+(?:AS\\s+)?(`[^`]+`|\\w+)$")
+
+	// Words can't be a table alias.
+	nonAliasWords = map[string]bool{
+		"WHERE": true, "SET": true, "ON": true, "USING": true, "JOIN": true, "LEFT": true, "RIGHT": true,
+		"INNER": true, "OUTER": true, "CROSS": true, "GROUP": true, "ORDER": true, "LIMIT": true, "HAVING": true,
+		"VALUES": true, "VALUE": true, "SELECT": true, "FOR": true, "LOCK": true, "UNION": true,
+	}
+
+	scanType = reflect.TypeOf(sql.RawBytes{})
+)
+
+// NewLoader creates a loader of a new database containing the tables.
+func NewLoader(tables ...*Table) (*datasrc.Loader, error) {
+	mu.Lock()
+	dataSourceName := "fakedb" + strconv.Itoa(len(databases))
+	databases[dataSourceName] = tables
+	mu.Unlock()
+	return datasrc.NewLoader("fakedb", dataSourceName)
+}
+
+func (d fakeDriver) Open(dataSourceName string) (driver.Conn, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	tables, found := databases[dataSourceName]
+	if !found {
+		return nil, fmt.Errorf("Unknown database %+q", dataSourceName)
+	}
+	return &fakeConn{tables: tables}, nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if _, err := c.resultColumns(query); err != nil {
+		return nil, err
+	}
+	return fakeStmt{}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("Transaction is not supported")
+}
+
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("Exec is not supported")
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, fmt.Errorf("Query is not supported")
+}
+
+func (c *fakeConn) table(tableName string) *Table {
+	for _, table := range c.tables {
+		if table.Name == tableName {
+			return table
+		}
+	}
+	return nil
+}
+
+// resultColumns checks table and column references in the query and returns result columns if it is a SELECT.
+func (c *fakeConn) resultColumns(query string) ([]*datasrc.Column, error) {
+
+	// Table references and aliases.
+	refs := map[string]*Table{}
+	ordered := []*Table{}
+	for _, k := range tableRefKeywordRe.FindAllStringIndex(query, -1) {
+		m := tableRefRe.FindStringSubmatch(query[k[1]:])
+		if m == nil {
+			continue
+		}
+		tableName := strings.Trim(m[1], "`")
+		table := c.table(tableName)
+		if table == nil {
+			return nil, fmt.Errorf("Table %+q doesn't exist", tableName)
+		}
+		refs[tableName] = table
+		if alias := strings.Trim(m[2], "`"); alias != "" && !nonAliasWords[strings.ToUpper(alias)] {
+			refs[alias] = table
+		}
+		ordered = append(ordered, table)
+	}
+
+	items := selectList(query)
+	if items == nil {
+		return nil, nil
+	}
+
+	ret := []*datasrc.Column{}
+	for _, item := range items {
+
+		name := ""
+		if m := aliasRe.FindStringSubmatch(item); m != nil && !strings.HasSuffix(m[1], ".") {
+			item, name = strings.TrimSpace(m[1]), strings.Trim(m[2], "`")
+		}
+
+		_, numErr := strconv.ParseFloat(item, 64)
+		m := colRefRe.FindStringSubmatch(item)
+		if m == nil || numErr == nil {
+			// Expressions.
+			if name == "" {
+				name = item
+			}
+			col := &datasrc.Column{
+				Name:        name,
+				ScanType:    scanType,
+				DataType:    "string",
+				HasNullable: true,
+				Nullable:    true,
+			}
+			if strings.HasPrefix(strings.ToUpper(item), "COUNT(") {
+				col.DataType, col.Nullable = "int64", false
+			} else if numErr == nil {
+				col.DataType, col.Nullable = "float64", false
+				if _, err := strconv.ParseInt(item, 10, 64); err == nil {
+					col.DataType = "int64"
+				}
+			}
+			ret = append(ret, col)
+			continue
+		}
+
+		tableRef, columnName := strings.Trim(m[1], "`"), strings.Trim(m[2], "`")
+		tables := ordered
+		if tableRef != "" {
+			table := refs[tableRef]
+			if table == nil {
+				return nil, fmt.Errorf("Unknown table %+q", tableRef)
+			}
+			tables = []*Table{table}
+		}
+
+		found := false
+		for _, table := range tables {
+			for _, column := range table.Columns {
+				if columnName != "*" && column.Name != columnName {
+					continue
+				}
+				if found && columnName != "*" {
+					return nil, fmt.Errorf("Column %+q is ambiguous", columnName)
+				}
+				found = true
+				col := &datasrc.Column{
+					Name:        column.Name,
+					ScanType:    scanType,
+					DataType:    column.DataType,
+					HasNullable: true,
+					Nullable:    column.Nullable,
+				}
+				if name != "" {
+					col.Name = name
+				}
+				ret = append(ret, col)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown column %+q", item)
+		}
+
+	}
+
+	// ORDER BY references a result column or a column of referenced tables.
+	for _, m := range orderByRe.FindAllStringSubmatch(query, -1) {
+		tableRef, columnName := strings.Trim(m[1], "`"), strings.Trim(m[2], "`")
+		if _, err := strconv.Atoi(columnName); err == nil && tableRef == "" {
+			continue
+		}
+		found := false
+		tables := ordered
+		if tableRef != "" {
+			tables = []*Table{refs[tableRef]}
+		} else {
+			for _, col := range ret {
+				found = found || col.Name == columnName
+			}
+		}
+		for _, table := range tables {
+			if table == nil {
+				continue
+			}
+			for _, column := range table.Columns {
+				found = found || column.Name == columnName
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown column %+q in 'order clause'", strings.TrimPrefix(tableRef+"."+columnName, "."))
+		}
+	}
+
+	return ret, nil
+
+}
+
+// selectList returns the items of the outermost select list or nil if it's not a SELECT query.
+func selectList(query string) []string {
+
+	query = strings.TrimSpace(query)
+	for strings.HasPrefix(query, "(") {
+		query = strings.TrimSpace(query[1:])
+	}
+	if len(query) < 6 || !strings.EqualFold(query[:6], "SELECT") {
+		return nil
+	}
+	query = query[6:]
+
+	items := []string{}
+	depth := 0
+	start := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'':
+			for i++; i < len(query) && query[i] != '\''; i++ {
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return append(items, strings.TrimSpace(query[start:i]))
+			}
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(query[start:i]))
+			start = i + 1
+		case depth == 0 && (c == ' ' || c == '\n' || c == '\t'):
+			rest := strings.ToUpper(strings.TrimSpace(query[i:]))
+			for _, word := range []string{"FROM ", "WHERE ", "GROUP ", "ORDER ", "LIMIT ", "FOR ", "LOCK "} {
+				if strings.HasPrefix(rest, word) {
+					return append(items, strings.TrimSpace(query[start:i]))
+				}
+			}
+		}
+	}
+	return append(items, strings.TrimSpace(query[start:]))
+
+}
+
+func (d fakeDriver) conn(conn *sql.Conn) (ret *fakeConn, err error) {
+	err = conn.Raw(func(driverConn interface{}) error {
+		ret = driverConn.(*fakeConn)
+		return nil
+	})
+	return
+}
+
+func (d fakeDriver) LoadQueryResultColumns(conn *sql.Conn, query string, args ...interface{}) ([]*datasrc.Column, error) {
+	c, err := d.conn(conn)
+	if err != nil {
+		return nil, err
+	}
+	return c.resultColumns(query)
+}
+
+func (d fakeDriver) LoadTableNames(conn *sql.Conn) ([]string, error) {
+	c, err := d.conn(conn)
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, table := range c.tables {
+		ret = append(ret, table.Name)
+	}
+	return ret, nil
+}
+
+func (d fakeDriver) LoadTableColumns(conn *sql.Conn, tableName string) ([]*datasrc.TableColumn, error) {
+	c, err := d.conn(conn)
+	if err != nil {
+		return nil, err
+	}
+	table := c.table(tableName)
+	if table == nil {
+		return nil, fmt.Errorf("Table %+q doesn't exist", tableName)
+	}
+	ret := []*datasrc.TableColumn{}
+	for i, column := range table.Columns {
+		ret = append(ret, &datasrc.TableColumn{
+			Column: datasrc.Column{
+				Name:        column.Name,
+				ScanType:    scanType,
+				HasNullable: true,
+				Nullable:    column.Nullable,
+				DataType:    column.DataType,
+			},
+			Pos:     i,
+			Comment: column.Comment,
+		})
+	}
+	return ret, nil
+}
+
+func (d fakeDriver) LoadIndexNames(conn *sql.Conn, tableName string) ([]string, error) {
+	c, err := d.conn(conn)
+	if err != nil {
+		return nil, err
+	}
+	if table := c.table(tableName); table != nil && len(table.Primary) != 0 {
+		return []string{"PRIMARY"}, nil
+	}
+	return nil, nil
+}
+
+func (d fakeDriver) LoadIndex(conn *sql.Conn, tableName, indexName string) ([]string, bool, bool, error) {
+	c, err := d.conn(conn)
+	if err != nil {
+		return nil, false, false, err
+	}
+	table := c.table(tableName)
+	if table == nil || indexName != "PRIMARY" {
+		return nil, false, false, fmt.Errorf("Index %+q of table %+q doesn't exist", indexName, tableName)
+	}
+	return table.Primary, true, true, nil
+}
+
+func (d fakeDriver) LoadFKNames(conn *sql.Conn, tableName string) ([]string, error) {
+	return nil, nil
+}
+
+func (d fakeDriver) LoadFK(conn *sql.Conn, tableName, fkName string) ([]string, string, []string, error) {
+	return nil, "", nil, fmt.Errorf("FK %+q of table %+q doesn't exist", fkName, tableName)
+}
+
+func (d fakeDriver) LoadAutoIncColumn(conn *sql.Conn, tableName string) (string, error) {
+	c, err := d.conn(conn)
+	if err != nil {
+		return "", err
+	}
+	if table := c.table(tableName); table != nil {
+		return table.AutoInc, nil
+	}
+	return "", nil
+}
+
+func (d fakeDriver) DataTypes() []string {
+	return []string{"float32", "float64", "bool", "int8", "uint8", "int16", "uint16", "int32", "uint32",
+		"int64", "uint64", "time", "bit", "json", "string"}
+}
+
+func (d fakeDriver) Quote(identifier string) string {
+	return "`" + strings.Replace(identifier, "`", "``", -1) + "`"
+}
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+	datasrc.RegistDriver("fakedb", fakeDriver{})
+}
@@ -0,0 +1,52 @@
+package fakedb
+
+import (
+	"testing"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/datasrc"
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+// Fixture is a fake database with its loaded DBInfo for tests of statements.
+type Fixture struct {
+	t      *testing.T
+	Loader *datasrc.Loader
+	DB     *infos.DBInfo
+}
+
+// NewFixture creates a fixture of a new database containing the tables. The test is failed immediately on error.
+func NewFixture(t *testing.T, tables ...*Table) *Fixture {
+
+	loader, err := NewLoader(tables...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := infos.NewDBInfo(loader)
+	if err != nil {
+		loader.Close()
+		t.Fatal(err)
+	}
+
+	return &Fixture{
+		t:      t,
+		Loader: loader,
+		DB:     db,
+	}
+
+}
+
+// Close closes the loader.
+func (f *Fixture) Close() {
+	f.Loader.Close()
+}
+
+// Stmt creates a StmtInfo from statement xml.
+func (f *Fixture) Stmt(s string, opts ...infos.StmtOption) (*infos.StmtInfo, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(s); err != nil {
+		f.t.Fatal(err)
+	}
+	return infos.NewStmtInfo(f.Loader, f.DB, doc.Root(), opts...)
+}
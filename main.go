@@ -41,7 +41,7 @@ func main() {
 	flag.StringVar(&dataSourceName, "dsn", "root:123456@tcp(localhost:3306)/dev?parseTime=true", "Data source name. ")
 	flag.StringVar(&outputDir, "out", "models", "Output directory for generated code.")
 	flag.StringVar(&outputPkg, "pkg", "", "Alternative package name of the generated code.")
-	flag.StringVar(&stmtDir, "stmt", "", "Statement xml directory.")
+	flag.StringVar(&stmtDir, "stmt", "", "Statement directory (*.xml or annotated *.sql files).")
 	flag.StringVar(&tmplDir, "tmpl", "", "Custom templates directory.")
 	flag.Var(&whitelist, "whitelist", "Comma seperated table names to render.")
 	flag.Var(&blacklist, "blacklist", "Comma seperated table names not to render.")
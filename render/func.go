@@ -9,9 +9,18 @@ import (
 	"github.com/huangjunwen/sqlw/datasrc"
 	"github.com/huangjunwen/sqlw/infos"
 	"github.com/huangjunwen/sqlw/infos/directives/arg"
+	"github.com/huangjunwen/sqlw/infos/directives/col"
+	_ "github.com/huangjunwen/sqlw/infos/directives/foreach"
+	"github.com/huangjunwen/sqlw/infos/directives/group"
+	_ "github.com/huangjunwen/sqlw/infos/directives/if"
+	_ "github.com/huangjunwen/sqlw/infos/directives/in"
+	"github.com/huangjunwen/sqlw/infos/directives/orderby"
+	"github.com/huangjunwen/sqlw/infos/directives/paginate"
 	_ "github.com/huangjunwen/sqlw/infos/directives/repl"
+	_ "github.com/huangjunwen/sqlw/infos/directives/set"
 	"github.com/huangjunwen/sqlw/infos/directives/vars"
 	"github.com/huangjunwen/sqlw/infos/directives/wc"
+	_ "github.com/huangjunwen/sqlw/infos/directives/where"
 )
 
 var (
@@ -89,6 +98,11 @@ func (r *Renderer) funcMap() template.FuncMap {
 			return camel(s, false)
 		},
 
+		// SingleLine joins lines of s so that it can be used in a "//" comment.
+		"SingleLine": func(s string) string {
+			return strings.Join(strings.Fields(s), " ")
+		},
+
 		"Literal": func(s string) string {
 			lines := []string{`"" +`}
 			for _, line := range strings.Split(s, "\n") {
@@ -110,7 +124,76 @@ func (r *Renderer) funcMap() template.FuncMap {
 			return scanType(col, 1)
 		},
 
+		// ArgType returns the Go type of an argument. Arguments derived from not nullable columns use plain Go types
+		// (e.g. "int32" instead of "null.Int32") if available.
+		"ArgType": func(arg *argdir.ArgInfo) (string, error) {
+			if arg.ArgType() != "" {
+				return arg.ArgType(), nil
+			}
+			if col := arg.ArgColumn().Col(); col != nil && col.HasNullable && !col.Nullable {
+				return scanType(arg.ArgColumn(), 2)
+			}
+			return scanType(arg.ArgColumn(), -1)
+		},
+
+		"ResultColIndex": wcdir.ResultColIndex,
+
+		// ResultColField returns the field (relative to the result struct) of the i-th result column.
+		"ResultColField": func(stmt *infos.StmtInfo, i int) string {
+			wildcards := wcdir.ExtractWildcardsInfo(stmt)
+			if column := wildcards.WildcardColumn(i); column.Valid() {
+				return camel(wildcards.WildcardName(i), true) + "." + camel(column.ColumnName(), true)
+			}
+			return camel(stmt.ResultCols()[i].Name, true)
+		},
+
+		// ResultColType returns the Go type of the i-th result column's field.
+		"ResultColType": func(stmt *infos.StmtInfo, i int) (string, error) {
+			if column := wcdir.ExtractWildcardsInfo(stmt).WildcardColumn(i); column.Valid() {
+				return scanType(column, -1)
+			}
+			if typ := coldir.ExtractColsInfo(stmt).ColumnType(i); typ != "" {
+				return typ, nil
+			}
+			return scanType(stmt.ResultCols()[i], -1)
+		},
+
+		// LineDirective returns a "//line" comment mapping the following lines to pos, or "" if pos is unknown.
+		// It must be on its own line.
+		"LineDirective": func(pos infos.SourcePos) string {
+			if !pos.Valid() {
+				return ""
+			}
+			return fmt.Sprintf("//line %s:%d", r.sourceFileName(pos.File), pos.Line)
+		},
+
+		// InlineLineDirective is the same as LineDirective but returns a "/*line*/" comment which can be used in the
+		// middle of a line.
+		"InlineLineDirective": func(pos infos.SourcePos) string {
+			if !pos.Valid() {
+				return ""
+			}
+			return fmt.Sprintf("/*line %s:%d*/ ", r.sourceFileName(pos.File), pos.Line)
+		},
+
+		// LineDirectiveReset returns a comment which will be replaced by a "//line" comment mapping the following
+		// lines back to the generated file, or "" if pos is unknown. It must be on its own line.
+		"LineDirectiveReset": func(pos infos.SourcePos) string {
+			if !pos.Valid() {
+				return ""
+			}
+			return lineDirectiveReset
+		},
+
+		"Errorf": func(format string, args ...interface{}) (string, error) {
+			return "", fmt.Errorf(format, args...)
+		},
+
 		"ExtractArgsInfo":      argdir.ExtractArgsInfo,
+		"ExtractColsInfo":      coldir.ExtractColsInfo,
+		"ExtractGroupInfo":     groupdir.ExtractGroupInfo,
+		"ExtractOrderByInfo":   orderbydir.ExtractOrderByInfo,
+		"ExtractPaginateInfo":  paginatedir.ExtractPaginateInfo,
 		"ExtractVarsInfo":      varsdir.ExtractVarsInfo,
 		"ExtractWildcardsInfo": wcdir.ExtractWildcardsInfo,
 	}
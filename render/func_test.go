@@ -0,0 +1,65 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/huangjunwen/sqlw/infos/directives/arg"
+	"github.com/huangjunwen/sqlw/internal/fakedb"
+)
+
+func TestArgType(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fixture := fakedb.NewFixture(t,
+		&fakedb.Table{
+			Name: "user",
+			Columns: []fakedb.Column{
+				{Name: "id", DataType: "int32"},
+				{Name: "email", DataType: "string", Nullable: true},
+				{Name: "birthday", DataType: "time"},
+				{Name: "profile", DataType: "json"},
+			},
+			Primary: []string{"id"},
+			AutoInc: "id",
+		},
+	)
+	defer fixture.Close()
+
+	scanTypeMap, err := NewScanTypeMap(fixture.Loader, strings.NewReader(`{
+  "int32":  ["null.Int32", "null.Int32", "int32"],
+  "string": ["null.String", "null.String", "string"],
+  "time":   ["null.Time", "null.Time", "time.Time"],
+  "json":   ["null.JSON", "null.JSON"]
+}`))
+	if !assert.NoError(err) {
+		return
+	}
+	r := &Renderer{scanTypeMap: scanTypeMap}
+	argType := r.funcMap()["ArgType"].(func(*argdir.ArgInfo) (string, error))
+
+	stmt, err := fixture.Stmt(`<stmt name="UpdateUser">
+  <arg name="id" type="@user.id" />
+  <arg name="email" type="@user.email" />
+  <arg name="birthday" type="@user.birthday" />
+  <arg name="profile" type="@user.profile" />
+  <arg name="name" type="string" />
+  UPDATE user SET email=:email, birthday=:birthday, profile=:profile WHERE id=:id OR :name=''
+</stmt>`)
+	if !assert.NoError(err) {
+		return
+	}
+
+	types := []string{}
+	for _, a := range argdir.ExtractArgsInfo(stmt).Args() {
+		typ, err := argType(a)
+		assert.NoError(err)
+		types = append(types, typ)
+	}
+	// Not nullable columns use plain Go types if available.
+	assert.Equal([]string{"int32", "null.String", "time.Time", "null.JSON", "string"}, types)
+
+}
@@ -0,0 +1,236 @@
+package render
+
+import (
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/huangjunwen/sqlw/internal/fakedb"
+)
+
+// Statements covering features of the mysql templates.
+const testStmts = `<stmt name="BlogByUser">
+  <arg name="userId" column="blog.user_id" />
+  <vars return="first" />
+  SELECT <wc table="blog" /> FROM blog WHERE user_id=:userId
+</stmt>
+<stmt name="AllBlogs">
+  SELECT <wc table="blog" as="b" />, <wc table="user" as="u" /> FROM blog AS b JOIN user AS u ON b.user_id=u.id
+</stmt>
+<stmt name="CountBlogs">
+  <vars return="scalar" />
+  SELECT <col name="cnt" type="int64" nullable="false">COUNT(*)</col> FROM blog
+</stmt>
+<stmt name="BlogTitleList">
+  <vars return="column" />
+  SELECT title FROM blog ORDER BY id
+</stmt>
+<stmt name="BlogsById">
+  <vars return="map" key="blog.id" />
+  SELECT <wc table="blog" /> FROM blog
+</stmt>
+<stmt name="BlogExists">
+  <arg name="id" column="blog.id" />
+  <vars return="exists" />
+  SELECT <col name="one" type="int" nullable="false">1</col> FROM blog WHERE id=:id
+</stmt>
+<stmt name="IterUsers">
+  <vars return="iter" reuse="true" />
+  SELECT <wc table="user" /> FROM user
+</stmt>
+<stmt name="UsersWithBlogs">
+  <group by="user" collect="blog" as="blogs" />
+  SELECT <wc table="user" />, <wc table="blog" nullable="true" /> FROM user LEFT JOIN blog ON blog.user_id=user.id ORDER BY user.id
+</stmt>
+<stmt name="PageBlogsByUser">
+  <arg name="userId" column="blog.user_id" />
+  <arg name="limit" type="int" />
+  SELECT <wc table="blog" /> FROM blog WHERE user_id=:userId AND <paginate by="-created_at,id" size-arg="limit" />
+</stmt>
+<stmt name="InsertUser">
+  <arg name="name" column="user.name" />
+  <vars return="lastInsertId" />
+  INSERT INTO user (name, created_at) VALUES (:name, NOW())
+</stmt>
+<stmt name="DeleteUser">
+  <arg name="id" column="user.id" />
+  <vars return="result" expect_rows="1" />
+  DELETE FROM user WHERE id=:id
+</stmt>
+<stmt name="TouchUser">
+  <arg name="id" column="user.id" />
+  <vars expect_rows="1" />
+  UPDATE user SET created_at=NOW() WHERE id=:id
+</stmt>
+<stmt name="SearchUsers">
+  <arg name="name" type="*string" />
+  <arg name="ids" type="[]int32" />
+  SELECT <wc table="user" /> FROM user
+  <where>
+    <if test="name != nil">AND name = :name</if>
+    <if test="len(ids) != 0">AND <in arg="ids">id</in></if>
+  </where>
+</stmt>
+`
+
+func TestGeneratedCode(t *testing.T) {
+
+	assert := assert.New(t)
+
+	// Generated code imports these packages.
+	imp := importer.ForCompiler(token.NewFileSet(), "source", nil)
+	for _, pkgPath := range []string{"gopkg.in/volatiletech/null.v6", "github.com/jmoiron/sqlx"} {
+		if _, err := imp.Import(pkgPath); err != nil {
+			t.Skipf("Dependency of generated code is not available: %s", err)
+		}
+	}
+
+	loader, err := fakedb.NewLoader(
+		&fakedb.Table{
+			Name: "user",
+			Columns: []fakedb.Column{
+				{Name: "id", DataType: "int32"},
+				{Name: "name", DataType: "string"},
+				{Name: "email", DataType: "string", Nullable: true},
+				{Name: "created_at", DataType: "time"},
+			},
+			Primary: []string{"id"},
+			AutoInc: "id",
+		},
+		&fakedb.Table{
+			Name: "blog",
+			Columns: []fakedb.Column{
+				{Name: "id", DataType: "int32"},
+				{Name: "user_id", DataType: "int32"},
+				{Name: "title", DataType: "string"},
+				{Name: "content", DataType: "string", Nullable: true},
+				{Name: "created_at", DataType: "time"},
+			},
+			Primary: []string{"id"},
+			AutoInc: "id",
+		},
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	defer loader.Close()
+
+	dir, err := ioutil.TempDir("", "sqlw")
+	if !assert.NoError(err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	stmtDir := path.Join(dir, "stmts")
+	outputDir := path.Join(dir, "models")
+	if !assert.NoError(os.Mkdir(stmtDir, 0755)) {
+		return
+	}
+	if !assert.NoError(ioutil.WriteFile(path.Join(stmtDir, "stmts.xml"), []byte(testStmts), 0644)) {
+		return
+	}
+
+	r, err := NewRenderer(
+		Loader(loader),
+		TmplFS(http.Dir("../templates/mysql")),
+		StmtDir(stmtDir),
+		OutputDir(outputDir),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.NoError(r.Run()) {
+		return
+	}
+
+	// Type check generated code.
+	fset := token.NewFileSet()
+	files := []*ast.File{}
+	fileInfos, err := ioutil.ReadDir(outputDir)
+	if !assert.NoError(err) {
+		return
+	}
+	for _, fileInfo := range fileInfos {
+		fileName := fileInfo.Name()
+		if !strings.HasSuffix(fileName, ".go") {
+			continue
+		}
+		if match, err := build.Default.MatchFile(outputDir, fileName); err != nil || !match {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path.Join(outputDir, fileName), nil, 0)
+		if !assert.NoError(err) {
+			return
+		}
+		files = append(files, file)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("models", fset, files, nil)
+	if !assert.NoError(err) {
+		return
+	}
+
+	typeString := func(name string) string {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			return ""
+		}
+		return types.TypeString(obj.Type(), types.RelativeTo(pkg))
+	}
+	methodString := func(typeName, name string) string {
+		obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(pkg.Scope().Lookup(typeName).Type()), false, pkg, name)
+		if obj == nil {
+			return ""
+		}
+		return types.TypeString(obj.Type(), types.RelativeTo(pkg))
+	}
+
+	for name, expect := range map[string]string{
+		// Return modes.
+		"BlogByUser":      "func(ctx context.Context, q Queryer, userId int32) (ret *BlogByUserResult, err error)",
+		"AllBlogs":        "func(ctx context.Context, q Queryer) (ret []*AllBlogsResult, err error)",
+		"CountBlogs":      "func(ctx context.Context, q Queryer) (ret int64, err error)",
+		"BlogTitleList":   "func(ctx context.Context, q Queryer) (ret []gopkg.in/volatiletech/null.v6.String, err error)",
+		"BlogsById":       "func(ctx context.Context, q Queryer) (ret map[gopkg.in/volatiletech/null.v6.Int32]*BlogsByIdResult, err error)",
+		"BlogExists":      "func(ctx context.Context, q Queryer, id int32) (ret bool, err error)",
+		"IterUsers":       "func(ctx context.Context, q Queryer) (ret *IterUsersIter, err error)",
+		"UsersWithBlogs":  "func(ctx context.Context, q Queryer) (ret []*UsersWithBlogsGroup, err error)",
+		"PageBlogsByUser": "func(ctx context.Context, q Queryer, userId int32, limit int, cursor *PageBlogsByUserCursor) (ret []*PageBlogsByUserResult, nextCursor *PageBlogsByUserCursor, err error)",
+		"InsertUser":      "func(ctx context.Context, e Execer, name string) (ret int64, err error)",
+		"DeleteUser":      "func(ctx context.Context, e Execer, id int32) (ret database/sql.Result, err error)",
+		"TouchUser":       "func(ctx context.Context, e Execer, id int32) (ret int64, err error)",
+		"SearchUsers":     "func(ctx context.Context, q Queryer, name *string, ids []int32) (ret []*SearchUsersResult, err error)",
+		// Hooks and prepared statements.
+		"NewHookedExecQueryer": "func(eq ExecQueryer, hooks Hooks) *HookedExecQueryer",
+		"PrepareAll":           "func(ctx context.Context, db Preparer) (*Statements, error)",
+	} {
+		assert.Equal(expect, typeString(name), name)
+	}
+
+	for name, expect := range map[string]string{
+		"Tx":          "func(tx *database/sql.Tx) *Statements",
+		"Close":       "func() error",
+		"BlogByUser":  "func(ctx context.Context, userId int32) (ret *BlogByUserResult, err error)",
+		"InsertUser":  "func(ctx context.Context, name string) (ret int64, err error)",
+		"SearchUsers": "",
+	} {
+		assert.Equal(expect, methodString("Statements", name), name)
+	}
+
+	// Group folding.
+	group := pkg.Scope().Lookup("UsersWithBlogsGroup").Type().Underlying()
+	assert.Equal("struct{User User; Blogs []*Blog}", types.TypeString(group, types.RelativeTo(pkg)))
+
+}
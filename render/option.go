@@ -28,7 +28,7 @@ func TmplFS(tmplFS http.FileSystem) Option {
 	}
 }
 
-// StmtDir sets the directory containing statement XMLs.
+// StmtDir sets the directory containing statement XMLs or annotated SQL files.
 func StmtDir(stmtDir string) Option {
 	return func(r *Renderer) error {
 		p := path.Clean(stmtDir)
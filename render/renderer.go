@@ -5,15 +5,22 @@ import (
 	"fmt"
 	"go/format"
 	"io/ioutil"
+	"log"
 	"net/http"
-	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"text/template"
 
 	"github.com/beevik/etree"
 	"github.com/huangjunwen/sqlw/datasrc"
 	"github.com/huangjunwen/sqlw/infos"
+	"github.com/huangjunwen/sqlw/infos/directives/fragment"
+)
+
+const (
+	// Placeholder of "//line" comment mapping the following lines back to the generated file.
+	lineDirectiveReset = "//sqlw:line-reset"
 )
 
 // Renderer is used for generating code.
@@ -63,55 +70,44 @@ func NewRenderer(opts ...Option) (*Renderer, error) {
 
 }
 
-func (r *Renderer) render(tmplName, fileName string, data interface{}) error {
+// render executes a template and returns formatted code, which is not written until all files are rendered.
+func (r *Renderer) render(tmplName, fileName string, data interface{}) ([]byte, error) {
 
 	// Open template if not exists.
 	tmpl := r.templates[tmplName]
 	if tmpl == nil {
 		tmplFile, err := r.tmplFS.Open(tmplName)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		tmplContent, err := ioutil.ReadAll(tmplFile)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		tmpl, err = template.New(tmplName).Funcs(r.funcMap()).Parse(string(tmplContent))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		r.templates[tmplName] = tmpl
 	}
 
-	// Open output file.
-	file, err := os.OpenFile(path.Join(r.outputDir, fileName), os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
 	// Render.
 	buf := &bytes.Buffer{}
 	if err := tmpl.Execute(buf, data); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Format.
 	fmtBuf, err := format.Source(buf.Bytes())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Write.
-	_, err = file.Write(fmtBuf)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// Line directives.
+	return resolveLineDirectives(fileName, fmtBuf), nil
 
 }
 
@@ -155,6 +151,27 @@ func (r *Renderer) Run() error {
 	}
 	r.scanTypeMap = scanTypeMap
 
+	// Errors of statements and rendering are collected to report all of them in one run. Nothing is written if there
+	// is any error.
+	errs := infos.ErrorList{}
+	fileNames := []string{}
+	contents := map[string][]byte{}
+	render := func(tmplName, fileName string, data interface{}) error {
+		content, err := r.render(tmplName, fileName, data)
+		if err != nil {
+			return err
+		}
+		fileNames = append(fileNames, fileName)
+		contents[fileName] = content
+		return nil
+	}
+
+	// Process statements.
+	stmtFileNames, stmtInfosList, err := r.processStmtFiles(&errs)
+	if err != nil {
+		return err
+	}
+
 	// Render tables.
 	for _, table := range r.db.Tables() {
 		if len(r.whitelist) != 0 {
@@ -166,73 +183,54 @@ func (r *Renderer) Run() error {
 				continue
 			}
 		}
-		if err := r.render(manifest.Templates.Table, "table_"+table.TableName()+".go", map[string]interface{}{
+		if err := render(manifest.Templates.Table, "table_"+table.TableName()+".go", map[string]interface{}{
 			"PackageName": r.outputPkg,
 			"Loader":      r.loader,
 			"DB":          r.db,
 			"Table":       table,
 		}); err != nil {
-			return err
+			errs.Add(err)
 		}
 		if manifest.Templates.TableTest != "" {
-			if err := r.render(manifest.Templates.TableTest, "table_"+table.TableName()+"_test.go", map[string]interface{}{
+			if err := render(manifest.Templates.TableTest, "table_"+table.TableName()+"_test.go", map[string]interface{}{
 				"PackageName": r.outputPkg,
 				"Loader":      r.loader,
 				"DB":          r.db,
 				"Table":       table,
 			}); err != nil {
-				return err
+				errs.Add(err)
 			}
 
 		}
 	}
 
 	// Render statements.
-	if r.stmtDir != "" {
-		stmtFileInfos, err := ioutil.ReadDir(r.stmtDir)
-		if err != nil {
-			return err
+	allStmtInfos := []*infos.StmtInfo{}
+	for i, stmtInfos := range stmtInfosList {
+		stmtFileName := stmtFileNames[i]
+		allStmtInfos = append(allStmtInfos, stmtInfos...)
+
+		// Files containing only fragments generate nothing.
+		if len(stmtInfos) == 0 {
+			continue
 		}
-		for _, stmtFileInfo := range stmtFileInfos {
-			if stmtFileInfo.IsDir() {
-				continue
-			}
-			stmtFileName := stmtFileInfo.Name()
-			if !strings.HasSuffix(stmtFileName, ".xml") {
-				continue
-			}
-			doc := etree.NewDocument()
-			if err := doc.ReadFromFile(path.Join(r.stmtDir, stmtFileName)); err != nil {
-				return err
-			}
-
-			stmtInfos := []*infos.StmtInfo{}
-			for _, elem := range doc.ChildElements() {
-				stmtInfo, err := infos.NewStmtInfo(r.loader, r.db, elem)
-				if err != nil {
-					return err
-				}
-				stmtInfos = append(stmtInfos, stmtInfo)
-			}
 
-			if err := r.render(manifest.Templates.Stmt, "stmt_"+stripSuffix(stmtFileName)+".go", map[string]interface{}{
+		if err := render(manifest.Templates.Stmt, "stmt_"+stripSuffix(stmtFileName)+".go", map[string]interface{}{
+			"PackageName": r.outputPkg,
+			"Loader":      r.loader,
+			"DB":          r.db,
+			"Stmts":       stmtInfos,
+		}); err != nil {
+			errs.Add(fmt.Errorf("%s: %s", stmtFileName, err))
+		}
+		if manifest.Templates.StmtTest != "" {
+			if err := render(manifest.Templates.StmtTest, "stmt_"+stripSuffix(stmtFileName)+"_test.go", map[string]interface{}{
 				"PackageName": r.outputPkg,
 				"Loader":      r.loader,
 				"DB":          r.db,
 				"Stmts":       stmtInfos,
 			}); err != nil {
-				return err
-			}
-			if manifest.Templates.StmtTest != "" {
-				if err := r.render(manifest.Templates.StmtTest, "stmt_"+stripSuffix(stmtFileName)+"_test.go", map[string]interface{}{
-					"PackageName": r.outputPkg,
-					"Loader":      r.loader,
-					"DB":          r.db,
-					"Stmts":       stmtInfos,
-				}); err != nil {
-					return err
-				}
-
+				errs.Add(fmt.Errorf("%s: %s", stmtFileName, err))
 			}
 
 		}
@@ -242,11 +240,33 @@ func (r *Renderer) Run() error {
 	for _, tmplName := range manifest.Templates.Extra {
 		// Render.
 		fileName := "extra_" + stripSuffix(tmplName) + ".go"
-		if err := r.render(tmplName, fileName, map[string]interface{}{
+		if err := render(tmplName, fileName, map[string]interface{}{
 			"PackageName": r.outputPkg,
 			"Loader":      r.loader,
 			"DB":          r.db,
+			"Stmts":       allStmtInfos,
 		}); err != nil {
+			errs.Add(err)
+		}
+	}
+
+	// Check collisions of Go identifiers across generated files.
+	symbols := newSymbolTable()
+	for _, fileName := range fileNames {
+		errs.Add(symbols.addFile(fileName, contents[fileName]))
+	}
+	errs.Add(symbols.Err())
+	for _, warning := range symbols.Warnings() {
+		log.Printf("[sqlw] Warning: %s\n", warning)
+	}
+
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	// Write.
+	for _, fileName := range fileNames {
+		if err := ioutil.WriteFile(path.Join(r.outputDir, fileName), contents[fileName], 0644); err != nil {
 			return err
 		}
 	}
@@ -254,6 +274,139 @@ func (r *Renderer) Run() error {
 	return nil
 }
 
+// processStmtFiles reads all statement files (*.xml or *.sql) in the statement directory and creates StmtInfos for
+// each file. Processing continues after errors in statements, all of them are added to errs and files with errors
+// are skipped.
+func (r *Renderer) processStmtFiles(errs *infos.ErrorList) (stmtFileNames []string, stmtInfosList [][]*infos.StmtInfo, err error) {
+
+	if r.stmtDir == "" {
+		return nil, nil, nil
+	}
+
+	stmtFileInfos, err := ioutil.ReadDir(r.stmtDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Read all statement files first to collect fragments.
+	docFileNames := []string{}
+	docs := []*etree.Document{}
+	fragments := fragmentdir.NewFragmentsInfo()
+	sources := infos.NewSourceMap()
+	// Statement files sharing the same base name (e.g. "blog.xml" and "blog.sql") would generate the same file.
+	baseNames := map[string]string{}
+	for _, stmtFileInfo := range stmtFileInfos {
+		if stmtFileInfo.IsDir() {
+			continue
+		}
+		stmtFileName := stmtFileInfo.Name()
+		var doc *etree.Document
+		switch {
+		case strings.HasSuffix(stmtFileName, ".xml"):
+			content, err := ioutil.ReadFile(path.Join(r.stmtDir, stmtFileName))
+			if err != nil {
+				return nil, nil, err
+			}
+			doc, err = sources.ReadXMLFile(stmtFileName, content)
+			if err != nil {
+				errs.Add(err)
+				continue
+			}
+		case strings.HasSuffix(stmtFileName, ".sql"):
+			// Annotated SQL file is translated into xml.
+			doc, err = readSQLStmtFile(r.stmtDir, stmtFileName, sources)
+			if err != nil {
+				errs.Add(err)
+				continue
+			}
+		default:
+			continue
+		}
+		if prev, found := baseNames[stripSuffix(stmtFileName)]; found {
+			errs.Add(fmt.Errorf("Statement file %+q and %+q generate the same file %+q", prev, stmtFileName,
+				"stmt_"+stripSuffix(stmtFileName)+".go"))
+			continue
+		}
+		baseNames[stripSuffix(stmtFileName)] = stmtFileName
+		for _, elem := range doc.ChildElements() {
+			if elem.Tag != "fragment" {
+				continue
+			}
+			if err := fragments.Add(elem); err != nil {
+				errs.Add(&infos.SourceError{
+					Pos: sources.Pos(elem),
+					Tag: elem.Tag,
+					Err: err,
+				})
+			}
+		}
+		docFileNames = append(docFileNames, stmtFileName)
+		docs = append(docs, doc)
+	}
+
+	for i, doc := range docs {
+		stmtInfos := []*infos.StmtInfo{}
+		hasErr := false
+		for _, elem := range doc.ChildElements() {
+			if elem.Tag == "fragment" {
+				continue
+			}
+			stmtInfo, err := infos.NewStmtInfo(r.loader, r.db, elem, fragmentdir.Fragments(fragments), infos.Sources(sources))
+			if err != nil {
+				errs.Add(err)
+				hasErr = true
+				continue
+			}
+			for _, warning := range stmtInfo.Warnings() {
+				log.Printf("[sqlw] Warning: %s\n", warning)
+			}
+			stmtInfos = append(stmtInfos, stmtInfo)
+		}
+		if hasErr {
+			continue
+		}
+		stmtFileNames = append(stmtFileNames, docFileNames[i])
+		stmtInfosList = append(stmtInfosList, stmtInfos)
+	}
+
+	return stmtFileNames, stmtInfosList, nil
+
+}
+
+// sourceFileName returns the path of a statement file relative to the output directory, which is used in line
+// directives of generated code.
+func (r *Renderer) sourceFileName(stmtFileName string) string {
+	stmtDir, err := filepath.Abs(r.stmtDir)
+	if err != nil {
+		return stmtFileName
+	}
+	outputDir, err := filepath.Abs(r.outputDir)
+	if err != nil {
+		return stmtFileName
+	}
+	dir, err := filepath.Rel(outputDir, stmtDir)
+	if err != nil {
+		dir = stmtDir
+	}
+	return filepath.ToSlash(filepath.Join(dir, stmtFileName))
+}
+
+// resolveLineDirectives replaces line directive reset placeholders in formatted code by "//line" comments mapping
+// the following lines back to the generated file itself.
+func resolveLineDirectives(fileName string, src []byte) []byte {
+	if !bytes.Contains(src, []byte(lineDirectiveReset)) {
+		return src
+	}
+	lines := strings.Split(string(src), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == lineDirectiveReset {
+			// NOTE: The directive applies to the next line, which is line i+2 (1-based).
+			lines[i] = fmt.Sprintf("//line %s:%d", fileName, i+2)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
 func stripSuffix(s string) string {
 	i := strings.LastIndexByte(s, '.')
 	if i < 0 {
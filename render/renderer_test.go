@@ -0,0 +1,57 @@
+package render
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+func TestResolveLineDirectives(t *testing.T) {
+
+	assert := assert.New(t)
+
+	src := "package models\n\n//line blog.xml:3\nfunc A() {\n}\n\n" + lineDirectiveReset + "\n\nfunc B() {\n}\n"
+	assert.Equal(
+		"package models\n\n//line blog.xml:3\nfunc A() {\n}\n\n//line stmt_blog.go:8\n\nfunc B() {\n}\n",
+		string(resolveLineDirectives("stmt_blog.go", []byte(src))),
+	)
+
+	src = "package models\n"
+	assert.Equal(src, string(resolveLineDirectives("stmt_blog.go", []byte(src))))
+
+}
+
+func TestStmtFileNameClash(t *testing.T) {
+
+	assert := assert.New(t)
+
+	stmtDir, err := ioutil.TempDir("", "sqlw")
+	if !assert.NoError(err) {
+		return
+	}
+	defer os.RemoveAll(stmtDir)
+
+	for fileName, content := range map[string]string{
+		"blog.xml": "<fragment name=\"blogColumns\">id, title</fragment>\n",
+		"blog.sql": "-- fragment: blogTitle\ntitle\n",
+		"user.sql": "-- fragment: userColumns\nid, name\n",
+	} {
+		if !assert.NoError(ioutil.WriteFile(path.Join(stmtDir, fileName), []byte(content), 0644)) {
+			return
+		}
+	}
+
+	r := &Renderer{stmtDir: stmtDir}
+	errs := infos.ErrorList{}
+	stmtFileNames, _, err := r.processStmtFiles(&errs)
+	if assert.NoError(err) {
+		assert.Equal([]string{"blog.sql", "user.sql"}, stmtFileNames)
+		assert.EqualError(errs.Err(), `Statement file "blog.sql" and "blog.xml" generate the same file "stmt_blog.go"`)
+	}
+
+}
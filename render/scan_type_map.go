@@ -11,7 +11,8 @@ import (
 // ScanTypeMap maps data type to scan type.
 // [0] is for not nullable types.
 // [1] is for nullable types.
-type ScanTypeMap map[string][2]string
+// [2] is for arguments of not nullable types, it's optional and defaults to [0].
+type ScanTypeMap map[string][3]string
 
 // NewScanTypeMap loads scan type map from io.Reader.
 func NewScanTypeMap(loader *datasrc.Loader, r io.Reader) (ScanTypeMap, error) {
@@ -24,7 +25,7 @@ func NewScanTypeMap(loader *datasrc.Loader, r io.Reader) (ScanTypeMap, error) {
 		v, ok := ret[dataType]
 		if !ok {
 			// If some data type is missing, filled it with "[]byte"
-			ret[dataType] = [2]string{"[]byte", "[]byte"}
+			ret[dataType] = [3]string{"[]byte", "[]byte", "[]byte"}
 			continue
 		}
 		if v[0] == "" {
@@ -33,6 +34,10 @@ func NewScanTypeMap(loader *datasrc.Loader, r io.Reader) (ScanTypeMap, error) {
 		if v[1] == "" {
 			return nil, fmt.Errorf("Data type %+q has no nullable scan type", dataType)
 		}
+		if v[2] == "" {
+			v[2] = v[0]
+			ret[dataType] = v
+		}
 	}
 	return ret, nil
 
@@ -0,0 +1,412 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+// Annotated SQL statement files are translated into xml documents, example:
+//
+//	-- name: BlogByUser :first
+//	-- arg: userId int
+//	SELECT /*wc:blog*/ FROM blog WHERE user_id=/*repl::userId*/1/*end*/
+//
+// is the same as:
+//
+//	<stmt name="BlogByUser">
+//	  <arg name="userId" type="int" />
+//	  <vars return="first" />
+//	  SELECT <wc table="blog" /> FROM blog WHERE user_id=<repl with=":userId">1</repl>
+//	</stmt>
+//
+// Header comments:
+//
+//	-- name: <stmtName> [:<return>]   starts a statement
+//	-- fragment: <fragmentName>       starts a fragment
+//	-- arg: <argName> [<type>] [column=<table.column>]
+//	-- vars: <key>=<value> ...
+//
+// Inline directives are written as "/*<tag>[:<main attribute>] <key>=<value> ...*/", directives with content
+// are closed by "/*end*/". <if> is written as "/*if <test>*/" and the expression of <in> is written as "expr=<expr>".
+
+var (
+	// Main attribute of each directive which can be written inline.
+	sqlDirectiveMainAttrs = map[string]string{
+		"wc":       "table",
+		"repl":     "with",
+		"col":      "name",
+		"include":  "ref",
+		"in":       "arg",
+		"if":       "",
+		"where":    "",
+		"set":      "",
+		"foreach":  "collection",
+		"group":    "by",
+		"paginate": "by",
+		"orderby":  "allowed",
+	}
+
+	// Directives with content.
+	sqlContainerDirectives = map[string]bool{
+		"repl":    true,
+		"col":     true,
+		"if":      true,
+		"where":   true,
+		"set":     true,
+		"foreach": true,
+	}
+
+	// Return modes in "-- name:" header, "many" and "exec" are the defaults.
+	sqlReturnModes = map[string]string{
+		"many":         "",
+		"exec":         "",
+		"one":          "one",
+		"first":        "first",
+		"scalar":       "scalar",
+		"column":       "column",
+		"map":          "map",
+		"exists":       "exists",
+		"iter":         "iter",
+		"execresult":   "result",
+		"execlastid":   "lastInsertId",
+		"lastInsertId": "lastInsertId",
+		"result":       "result",
+	}
+)
+
+// readSQLStmtFile reads an annotated SQL statement file and translates it into an xml document. Positions of
+// elements are recorded in sources.
+func readSQLStmtFile(dir, fileName string, sources *infos.SourceMap) (*etree.Document, error) {
+	file, err := os.Open(path.Join(dir, fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	doc, err := parseSQLStmts(fileName, file, sources)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s", fileName, err)
+	}
+	return doc, nil
+}
+
+func parseSQLStmts(fileName string, r io.Reader, sources *infos.SourceMap) (*etree.Document, error) {
+
+	doc := etree.NewDocument()
+	setPos := func(elem *etree.Element, line, col int) {
+		sources.SetPos(elem, infos.SourcePos{
+			File: fileName,
+			Line: line,
+			Col:  col,
+		})
+	}
+
+	var (
+		elem      *etree.Element // current <stmt> or <fragment>
+		body      []string
+		bodyStart int
+	)
+
+	flush := func() error {
+		if elem == nil {
+			return nil
+		}
+		if err := parseSQLBody(elem, strings.Join(body, "\n"), bodyStart, setPos); err != nil {
+			return err
+		}
+		elem = nil
+		body = nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+
+		key, value, ok := sqlHeader(line)
+		col := strings.Index(line, "--") + 1
+		if !ok {
+			if elem == nil {
+				if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+					return nil, fmt.Errorf("%d: SQL outside statement", lineNo)
+				}
+				continue
+			}
+			if len(body) == 0 {
+				bodyStart = lineNo
+			}
+			body = append(body, line)
+			continue
+		}
+
+		switch key {
+		case "name", "fragment":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("%d: Missing name in %+q", lineNo, line)
+			}
+			if key == "fragment" {
+				if len(fields) != 1 {
+					return nil, fmt.Errorf("%d: Invalid fragment header %+q", lineNo, line)
+				}
+				elem = doc.CreateElement("fragment")
+				elem.CreateAttr("name", fields[0])
+				setPos(elem, lineNo, col)
+				break
+			}
+			if len(fields) > 2 || (len(fields) == 2 && !strings.HasPrefix(fields[1], ":")) {
+				return nil, fmt.Errorf("%d: Invalid name header %+q", lineNo, line)
+			}
+			elem = doc.CreateElement("stmt")
+			elem.CreateAttr("name", fields[0])
+			setPos(elem, lineNo, col)
+			if len(fields) == 2 {
+				ret, found := sqlReturnModes[fields[1][1:]]
+				if !found {
+					return nil, fmt.Errorf("%d: Unknown return mode %+q", lineNo, fields[1])
+				}
+				if ret != "" {
+					elem.CreateElement("vars").CreateAttr("return", ret)
+				}
+			}
+
+		case "arg":
+			if elem == nil || elem.Tag != "stmt" {
+				return nil, fmt.Errorf("%d: Arg header outside statement", lineNo)
+			}
+			fields, err := sqlFields(value)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %s", lineNo, err)
+			}
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("%d: Missing arg name in %+q", lineNo, line)
+			}
+			arg := elem.CreateElement("arg")
+			arg.CreateAttr("name", fields[0])
+			setPos(arg, lineNo, col)
+			for i, field := range fields[1:] {
+				if k, v, ok := sqlAttr(field); ok {
+					arg.CreateAttr(k, v)
+				} else if i == 0 {
+					arg.CreateAttr("type", field)
+				} else {
+					return nil, fmt.Errorf("%d: Invalid arg header %+q", lineNo, line)
+				}
+			}
+
+		case "vars":
+			if elem == nil || elem.Tag != "stmt" {
+				return nil, fmt.Errorf("%d: Vars header outside statement", lineNo)
+			}
+			fields, err := sqlFields(value)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %s", lineNo, err)
+			}
+			vars := elem.CreateElement("vars")
+			setPos(vars, lineNo, col)
+			for _, field := range fields {
+				k, v, _ := sqlAttr(field)
+				vars.CreateAttr(k, v)
+			}
+
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+
+}
+
+// sqlHeader parses header comment like "-- name: BlogByUser :many".
+func sqlHeader(line string) (key, value string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "--") {
+		return "", "", false
+	}
+	trimmed = strings.TrimSpace(trimmed[2:])
+	i := strings.IndexByte(trimmed, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	key = trimmed[:i]
+	switch key {
+	case "name", "fragment", "arg", "vars":
+		return key, strings.TrimSpace(trimmed[i+1:]), true
+	}
+	return "", "", false
+}
+
+// parseSQLBody translates SQL text with inline directives into children of elem. The body starts at line lineNo.
+func parseSQLBody(elem *etree.Element, body string, lineNo int, setPos func(*etree.Element, int, int)) error {
+
+	stack := []*etree.Element{elem}
+	top := func() *etree.Element {
+		return stack[len(stack)-1]
+	}
+
+	// Current position of body.
+	col := 1
+	advance := func(text string) {
+		if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+			lineNo += strings.Count(text, "\n")
+			col = len(text) - i
+			return
+		}
+		col += len(text)
+	}
+
+	for {
+		i := strings.Index(body, "/*")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(body[i+2:], "*/")
+		if j < 0 {
+			break
+		}
+		comment := body[i+2 : i+2+j]
+		tag, main, rest := sqlDirective(comment)
+		if tag == "" {
+			// Normal comment.
+			top().CreateCharData(body[:i+2+j+2])
+			advance(body[:i+2+j+2])
+			body = body[i+2+j+2:]
+			continue
+		}
+
+		top().CreateCharData(body[:i])
+		advance(body[:i])
+		line, col0 := lineNo, col
+		advance(body[i : i+2+j+2])
+		body = body[i+2+j+2:]
+
+		if tag == "end" {
+			if len(stack) == 1 {
+				return fmt.Errorf("%d: Unexpected /*end*/", line)
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		directive := top().CreateElement(tag)
+		setPos(directive, line, col0)
+		if mainAttr := sqlDirectiveMainAttrs[tag]; mainAttr != "" && main != "" {
+			directive.CreateAttr(mainAttr, main)
+		}
+		if tag == "if" {
+			directive.CreateAttr("test", strings.TrimSpace(rest))
+		} else {
+			fields, err := sqlFields(rest)
+			if err != nil {
+				return fmt.Errorf("%d: %s", line, err)
+			}
+			for _, field := range fields {
+				k, v, ok := sqlAttr(field)
+				if !ok {
+					return fmt.Errorf("%d: Invalid attribute %+q in /*%s*/", line, field, comment)
+				}
+				if tag == "in" && k == "expr" {
+					// Expression of <in> is its content.
+					directive.CreateCharData(v)
+					continue
+				}
+				directive.CreateAttr(k, v)
+			}
+		}
+		if sqlContainerDirectives[tag] {
+			stack = append(stack, directive)
+		}
+	}
+
+	top().CreateCharData(body)
+	advance(body)
+	if len(stack) != 1 {
+		return fmt.Errorf("%d: Missing /*end*/ for /*%s*/", lineNo, top().Tag)
+	}
+	return nil
+
+}
+
+// sqlDirective parses inline directive comment like "wc:blog as=b". It returns tag "" if it is not a directive.
+func sqlDirective(comment string) (tag, main, rest string) {
+	if comment == "end" {
+		return "end", "", ""
+	}
+	end := strings.IndexAny(comment, ": \t\n")
+	if end < 0 {
+		end = len(comment)
+	}
+	tag = comment[:end]
+	if _, found := sqlDirectiveMainAttrs[tag]; !found {
+		return "", "", ""
+	}
+	rest = comment[end:]
+	if strings.HasPrefix(rest, ":") {
+		rest = rest[1:]
+		end = strings.IndexAny(rest, " \t\n")
+		if end < 0 {
+			end = len(rest)
+		}
+		main, rest = rest[:end], rest[end:]
+	}
+	return tag, main, rest
+}
+
+// sqlFields splits s into fields by spaces, double quoted strings are kept as a whole.
+func sqlFields(s string) ([]string, error) {
+	fields := []string{}
+	field := []byte{}
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+			field = append(field, c)
+		case !inQuote && (c == ' ' || c == '\t' || c == '\n' || c == '\r'):
+			if len(field) != 0 {
+				fields = append(fields, string(field))
+				field = field[:0]
+			}
+		default:
+			field = append(field, c)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("Unterminated quote in %+q", s)
+	}
+	if len(field) != 0 {
+		fields = append(fields, string(field))
+	}
+	return fields, nil
+}
+
+// sqlAttr parses field like `key=value` or `key="quoted value"`. A field without '=' is an attribute with empty value.
+func sqlAttr(field string) (key, value string, ok bool) {
+	i := strings.IndexByte(field, '=')
+	if i < 0 {
+		return field, "", false
+	}
+	key, value = field[:i], field[i+1:]
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	return key, value, true
+}
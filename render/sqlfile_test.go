@@ -0,0 +1,49 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQLStmts(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, testCase := range []struct {
+		SQL string
+		XML string
+		Err bool
+	}{
+		{
+			"-- name: BlogByUser :first\n-- arg: userId int\nSELECT /*wc:blog*/ FROM blog WHERE user_id=/*repl::userId*/1/*end*/ AND a<b\n",
+			`<stmt name="BlogByUser"><vars return="first"/><arg name="userId" type="int"/>SELECT <wc table="blog"/> FROM blog WHERE user_id=<repl with=":userId">1</repl> AND a&lt;b</stmt>`,
+			false,
+		},
+		{
+			"-- fragment: visible\nb.hidden=0 /* note */\n",
+			`<fragment name="visible">b.hidden=0 /* note */</fragment>`,
+			false,
+		},
+		{
+			"-- name: Search\n-- arg: name *string\nSELECT 1 /*where*//*if name != nil*/AND name=:name/*end*//*end*/",
+			`<stmt name="Search"><arg name="name" type="*string"/>SELECT 1 <where><if test="name != nil">AND name=:name</if></where></stmt>`,
+			false,
+		},
+		{"SELECT 1", "", true},
+		{"-- name: X :unknown\nSELECT 1", "", true},
+		{"-- name: X\nSELECT /*if a*/1", "", true},
+		{"-- name: X\nSELECT 1/*end*/", "", true},
+	} {
+		doc, err := parseSQLStmts("test.sql", strings.NewReader(testCase.SQL), nil)
+		if testCase.Err {
+			assert.Error(err, "SQL: %+q", testCase.SQL)
+			continue
+		}
+		assert.NoError(err, "SQL: %+q", testCase.SQL)
+		xml, _ := doc.WriteToString()
+		assert.Equal(testCase.XML, xml, "SQL: %+q", testCase.SQL)
+	}
+
+}
@@ -0,0 +1,218 @@
+package render
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/huangjunwen/sqlw/infos"
+)
+
+// symbolTable is a registry of Go identifiers declared in generated code. Collisions are detected before writing
+// generated files since they surface as compile errors in generated code otherwise.
+//
+// Identifiers are collected by parsing generated code, so identifiers produced by any template (including custom
+// ones) are covered. Positions are resolved by line directives in generated code, e.g. functions of a statement are
+// located in its statement file.
+type symbolTable struct {
+	fset     *token.FileSet
+	symbols  map[string][]symbolOrigin // "pkg.scope.ident" -> origins, scope is "" for package level identifiers
+	fields   map[string][]symbolOrigin // "pkg.type.lowerident" -> origins of struct fields
+	errs     infos.ErrorList
+	warnings infos.ErrorList
+}
+
+// symbolOrigin describes where an identifier is declared.
+type symbolOrigin struct {
+	name       string // e.g. "User" or "UserResult.Id"
+	kind       string // e.g. "type", "func", "method" or "field"
+	pos        token.Position
+	constraint string // build constraint of the file, e.g. "// +build go1.10"
+}
+
+func newSymbolTable() *symbolTable {
+	return &symbolTable{
+		fset:    token.NewFileSet(),
+		symbols: map[string][]symbolOrigin{},
+		fields:  map[string][]symbolOrigin{},
+	}
+}
+
+// addFile parses a generated file and registers its top level identifiers, methods and struct fields.
+func (t *symbolTable) addFile(fileName string, src []byte) error {
+
+	file, err := parser.ParseFile(t.fset, fileName, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	pkg := file.Name.Name
+	constraint := buildConstraint(file)
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				// NOTE: Multiple init functions are allowed.
+				if d.Name.Name != "init" {
+					t.add(pkg, "", d.Name, "func", constraint)
+				}
+				continue
+			}
+			if typeName := recvTypeName(d.Recv.List[0].Type); typeName != "" {
+				t.add(pkg, typeName, d.Name, "method", constraint)
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					t.add(pkg, "", s.Name, "type", constraint)
+					if st, ok := s.Type.(*ast.StructType); ok {
+						t.addFields(pkg, s.Name.Name, st, constraint)
+					}
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range s.Names {
+						t.add(pkg, "", name, kind, constraint)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+
+}
+
+// addFields registers fields of a struct type.
+func (t *symbolTable) addFields(pkg, typeName string, st *ast.StructType, constraint string) {
+	for _, field := range st.Fields.List {
+		names := field.Names
+		if len(names) == 0 {
+			// Embedded field.
+			if name := embeddedName(field.Type); name != nil {
+				names = []*ast.Ident{name}
+			}
+		}
+		for _, name := range names {
+			if name.Name == "_" {
+				continue
+			}
+			t.add(pkg, typeName, name, "field", constraint)
+
+			// NOTE: Fields differing only in case (e.g. "UserId" from column "user_id" and "Userid" from column
+			// "userId") compile but are warned since encoding/json matches field names case-insensitively.
+			origin := t.origin(typeName+"."+name.Name, "field", name, constraint)
+			key := pkg + "." + typeName + "." + strings.ToLower(name.Name)
+			if prev := findOrigin(t.fields[key], origin); prev != nil && prev.name != origin.name {
+				t.warnings.Add(fmt.Errorf("%s: Go field %+q differs only in case from field %+q declared at %s",
+					origin.pos.String(), origin.name, prev.name, prev.pos.String()))
+			}
+			t.fields[key] = append(t.fields[key], origin)
+		}
+	}
+}
+
+// add registers an identifier in scope ("" for package level, otherwise a type name). A collision error is
+// recorded if it already exists.
+func (t *symbolTable) add(pkg, scope string, ident *ast.Ident, kind, constraint string) {
+
+	if ident.Name == "_" {
+		return
+	}
+
+	name := ident.Name
+	if scope != "" {
+		name = scope + "." + ident.Name
+	}
+	origin := t.origin(name, kind, ident, constraint)
+
+	key := pkg + "." + scope + "." + ident.Name
+	if prev := findOrigin(t.symbols[key], origin); prev != nil {
+		t.collide(origin, *prev)
+	}
+	t.symbols[key] = append(t.symbols[key], origin)
+
+}
+
+// find returns the first origin in prevs which can be compiled together with origin, or nil if not found.
+//
+// NOTE: Files with different build constraints (e.g. "go1.10" and "!go1.10") are assumed to be exclusive.
+func findOrigin(prevs []symbolOrigin, origin symbolOrigin) *symbolOrigin {
+	for i, prev := range prevs {
+		if prev.constraint == "" || origin.constraint == "" || prev.constraint == origin.constraint {
+			return &prevs[i]
+		}
+	}
+	return nil
+}
+
+func (t *symbolTable) origin(name, kind string, ident *ast.Ident, constraint string) symbolOrigin {
+	return symbolOrigin{
+		name:       name,
+		kind:       kind,
+		pos:        t.fset.Position(ident.Pos()),
+		constraint: constraint,
+	}
+}
+
+func (t *symbolTable) collide(origin, prev symbolOrigin) {
+	t.errs.Add(fmt.Errorf("%s: Go %s %+q collides with %s %+q declared at %s", origin.pos.String(), origin.kind, origin.name, prev.kind, prev.name, prev.pos.String()))
+}
+
+// Err returns collision errors or nil if there is no collision.
+func (t *symbolTable) Err() error {
+	return t.errs.Err()
+}
+
+// Warnings returns non fatal issues found, e.g. struct fields differing only in case.
+func (t *symbolTable) Warnings() []error {
+	return t.warnings
+}
+
+// recvTypeName returns the type name of a method receiver, e.g. "User" for "(u *User)".
+func recvTypeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return recvTypeName(e.X)
+	case *ast.ParenExpr:
+		return recvTypeName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// embeddedName returns the field name of an embedded field, e.g. "Time" for "*time.Time".
+func embeddedName(expr ast.Expr) *ast.Ident {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.Ident:
+		return e
+	}
+	return nil
+}
+
+// buildConstraint returns build constraint lines of a file, or "" if it has none.
+func buildConstraint(file *ast.File) string {
+	lines := []string{}
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if strings.HasPrefix(comment.Text, "// +build ") || strings.HasPrefix(comment.Text, "//go:build ") {
+				lines = append(lines, comment.Text)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymbolTable(t *testing.T) {
+
+	assert := assert.New(t)
+
+	symbols := newSymbolTable()
+	assert.NoError(symbols.addFile("table_user.go", []byte(`package models
+
+type User struct {
+	Id     int
+	UserId int
+}
+
+func (u *User) Insert() {}
+
+func init() {}
+`)))
+	assert.NoError(symbols.addFile("extra_helper.go", []byte(`package models
+
+var _ = 1
+
+const UserSortDefault = 0
+
+func init() {}
+`)))
+	assert.NoError(symbols.Err())
+
+	assert.NoError(symbols.addFile("stmt_user.go", []byte(`package models
+
+type UserResult struct {
+	Userid int
+	User   *User
+}
+
+//line ../stmts/user.xml:3
+func User() {
+//line stmt_user.go:11
+}
+
+type UserSort int
+
+const (
+	UserSortDefault UserSort = iota
+)
+
+type Statements struct {
+	tx int
+}
+
+func (stmts *Statements) Tx() {}
+`)))
+	assert.NoError(symbols.addFile("stmt_other.go", []byte(`package models
+
+type UserResult struct{}
+
+func (u User) Insert() {}
+`)))
+	assert.EqualError(symbols.Err(), `../stmts/user.xml:3: Go func "User" collides with type "User" declared at table_user.go:3:6
+stmt_user.go:16: Go const "UserSortDefault" collides with const "UserSortDefault" declared at extra_helper.go:5:7
+stmt_other.go:3:6: Go type "UserResult" collides with type "UserResult" declared at stmt_user.go:3:6
+stmt_other.go:5:15: Go method "User.Insert" collides with method "User.Insert" declared at table_user.go:8:16`)
+
+	symbols = newSymbolTable()
+	assert.NoError(symbols.addFile("table_user.go", []byte(`package models
+
+type User struct {
+	UserId int
+	Userid int
+}
+`)))
+	assert.NoError(symbols.Err())
+	if assert.Len(symbols.Warnings(), 1) {
+		assert.EqualError(symbols.Warnings()[0], `table_user.go:5:2: Go field "User.Userid" differs only in case from field "User.UserId" declared at table_user.go:4:2`)
+	}
+	assert.NoError(symbols.addFile("stmt_user.go", []byte(`package models
+
+type User struct {
+	UserId int
+}
+`)))
+	assert.Len(symbols.Warnings(), 1)
+	assert.EqualError(symbols.Err(), `stmt_user.go:3:6: Go type "User" collides with type "User" declared at table_user.go:3:6
+stmt_user.go:4:2: Go field "User.UserId" collides with field "User.UserId" declared at table_user.go:4:2`)
+
+	// Files with different build constraints.
+	symbols = newSymbolTable()
+	assert.NoError(symbols.addFile("extra_a.go", []byte("// +build go1.10\n\npackage models\n\nfunc newA() {}\n")))
+	assert.NoError(symbols.addFile("extra_a_pre_1.10.go", []byte("// +build !go1.10\n\npackage models\n\nfunc newA() {}\n")))
+	assert.NoError(symbols.Err())
+	assert.NoError(symbols.addFile("extra_b.go", []byte("package models\n\nfunc newA() {}\n")))
+	assert.EqualError(symbols.Err(), `extra_b.go:3:6: Go func "newA" collides with func "newA" declared at extra_a.go:5:6`)
+
+}